@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+)
+
+type (
+	// apiClient struct talk to running resilia http server
+	apiClient struct {
+		server string
+		client *http.Client
+	}
+
+	// runResponse struct define response body of suite run
+	runResponse struct {
+		RunID            string                         `json:"run_id"`
+		SuiteID          string                         `json:"suite_id"`
+		Status           services.RunStatus             `json:"status"`
+		Deadline         *time.Time                     `json:"deadline"`
+		CreatedResources map[services.KubeKind][]string `json:"created_resources"`
+	}
+
+	// stopResponse struct define response body of stopped run
+	stopResponse struct {
+		Run      services.Run            `json:"run"`
+		Teardown services.TeardownReport `json:"teardown"`
+	}
+
+	// apiError struct define error response body of resilia server
+	apiError struct {
+		Status  int             `json:"-"`
+		Message string          `json:"error"`
+		RunID   string          `json:"run_id,omitempty"`
+		Errors  json.RawMessage `json:"errors,omitempty"`
+		Fields  json.RawMessage `json:"fields,omitempty"`
+	}
+)
+
+// newAPIClient function return client of resilia server on given url
+func newAPIClient(server string) *apiClient {
+	return &apiClient{
+		server: strings.TrimSuffix(server, "/"),
+		client: &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+// Error function return server error message with it's details
+func (e *apiError) Error() string {
+	msg := fmt.Sprintf("server responded %d: %s", e.Status, e.Message)
+	if e.RunID != "" {
+		msg += fmt.Sprintf(" (run %s)", e.RunID)
+	}
+	return msg
+}
+
+// RunSuite function run suite document on server
+func (c *apiClient) RunSuite(document []byte) (runResponse, error) {
+	var resp runResponse
+	err := c.do(http.MethodPost, "/suites/", "application/yaml", bytes.NewReader(document), &resp)
+	return resp, err
+}
+
+// StopRun function stop suite run on server
+// teardown report returned even when some resources failed to be removed
+func (c *apiClient) StopRun(runID string) (stopResponse, error) {
+	var resp stopResponse
+	err := c.do(http.MethodPost, "/runs/"+runID+"/stop", "", nil, &resp)
+	return resp, err
+}
+
+// GetRuns function return all suite runs on server
+func (c *apiClient) GetRuns() ([]services.Run, error) {
+	var runs []services.Run
+	err := c.do(http.MethodGet, "/runs/", "", nil, &runs)
+	return runs, err
+}
+
+// GetSuites function return all stored suites on server
+func (c *apiClient) GetSuites() ([]services.Model, error) {
+	var suites []services.Model
+	err := c.do(http.MethodGet, "/suites/", "", nil, &suites)
+	return suites, err
+}
+
+// do function send request to server and decode response body into out
+// response body decoded even on error status, error returned as *apiError
+func (c *apiClient) do(method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequest(method, c.server+path, body)
+	if err != nil {
+		return err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &apiError{Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		json.Unmarshal(data, apiErr)
+
+		// stop response still carry teardown report on failure
+		if out != nil {
+			json.Unmarshal(data, out)
+		}
+		return apiErr
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+)
+
+// gcCommand function delete orphaned objects created by resilia
+func gcCommand(args []string) error {
+	var (
+		conf config
+		opts services.GCOptions
+		fs   = newFlagSet("gc", &conf, false)
+	)
+
+	fs.BoolVar(&opts.DryRun, "dry_run", false, "only list orphaned objects without deleting them")
+	fs.DurationVar(&opts.MaxAge, "max_age", 24*time.Hour, "unfinished run started longer than this considered expired, 0 never expire")
+	fs.Parse(args)
+
+	s, err := newStack(conf)
+	if err != nil {
+		return err
+	}
+	defer s.suiteResource.Close()
+
+	report, err := s.suiteService.CollectGarbage(opts)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "OBJECT\tRUN\tREASON\tRESULT")
+	for _, g := range report.Collected {
+		result := "deleted"
+		if report.DryRun {
+			result = "would delete"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", g.Object, g.RunID, g.Reason, result)
+	}
+	for _, g := range report.Failed {
+		fmt.Fprintf(w, "%s\t%s\t%s\tfailed: %s\n", g.Object, g.RunID, g.Reason, g.Error)
+	}
+	w.Flush()
+
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("fail to delete %d orphaned object(s)", len(report.Failed))
+	}
+
+	return nil
+}
+
+// collectGarbage function run garbage collection every given interval
+// meant to run on it's own goroutine along with http server
+func collectGarbage(suiteService *services.Service, interval time.Duration, opts services.GCOptions) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		report, err := suiteService.CollectGarbage(opts)
+		if err != nil {
+			log.Println("garbage collection failed: ", err)
+			continue
+		}
+
+		for _, g := range report.Collected {
+			log.Printf("garbage collected %s of run %s (%s)", g.Object, g.RunID, g.Reason)
+		}
+		for _, g := range report.Failed {
+			log.Printf("fail to collect garbage %s of run %s (%s): %s", g.Object, g.RunID, g.Reason, g.Error)
+		}
+	}
+}
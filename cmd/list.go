@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/resouces"
+	"github.com/faruqisan/resilia/engine/suites/services"
+)
+
+// listCommand function print suite runs, or stored suites
+// only storage needed when run locally, no cluster access required
+func listCommand(args []string) error {
+	var (
+		conf   config
+		suites bool
+		fs     = newFlagSet("list", &conf, true)
+	)
+
+	fs.BoolVar(&suites, "suites", false, "list stored suites instead of runs")
+	fs.Parse(args)
+
+	var (
+		client        *apiClient
+		suiteResource resouces.SuiteStore
+	)
+
+	if conf.server != "" {
+		client = newAPIClient(conf.server)
+	} else {
+		var err error
+		suiteResource, err = newStore(conf)
+		if err != nil {
+			return err
+		}
+		defer suiteResource.Close()
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	if suites {
+		var (
+			models []services.Model
+			err    error
+		)
+
+		if client != nil {
+			models, err = client.GetSuites()
+		} else {
+			models, err = suiteResource.Get()
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, "ID\tNAME\tPUMBA WORKERS")
+		for _, m := range models {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", m.ID, m.Name, len(m.PumbaWorkers))
+		}
+		return nil
+	}
+
+	var (
+		runs []services.Run
+		err  error
+	)
+
+	if client != nil {
+		runs, err = client.GetRuns()
+	} else {
+		runs, err = suiteResource.GetRuns()
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "ID\tSUITE\tSTATUS\tSTARTED\tENDED")
+	for _, run := range runs {
+		ended := "-"
+		if run.EndedAt != nil {
+			ended = run.EndedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", run.ID, run.SuiteID, run.Status, run.StartedAt.Format(time.RFC3339), ended)
+	}
+	return nil
+}
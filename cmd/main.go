@@ -1,52 +1,181 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strings"
 	"time"
 
-	httpserver "github.com/faruqisan/resilia/engine/servers/http"
+	"github.com/faruqisan/resilia/engine/suites/resouces"
 	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/pkg/cache"
 	"github.com/faruqisan/resilia/pkg/kube"
 	"github.com/faruqisan/resilia/pkg/pumba"
 )
 
-var (
-	inCluster bool
-	httpPort  string
+const usage = `resilia run chaos suites on k8s cluster
+
+Usage:
+  resilia <command> [flags] [args]
+
+Commands:
+  serve                 run resilia http server (default)
+  run <suite.yaml>      apply suite, inject chaos, wait for ctrl-c or -duration then tear down
+  validate <suite.yaml> check suite document without touching the cluster
+  stop <run-id>         stop suite run and delete it's created resources
+  list                  list suite runs, or stored suites with -suites
+  gc                    delete orphaned objects created by resilia, -dry_run to only list them
+
+Run "resilia <command> -h" for command's flags
+`
+
+// suites storage backends selectable by -store flag
+const (
+	storeRedis  = "redis"
+	storeBolt   = "bolt"
+	storeMemory = "memory"
 )
 
+type (
+	// command struct define single cli sub command
+	command struct {
+		run func(args []string) error
+	}
+
+	// config struct hold flags shared across commands
+	// used to setup resilia engines
+	config struct {
+		inCluster bool
+		store     string
+		redisHost string
+		boltPath  string
+		retention time.Duration
+		server    string
+	}
+
+	// stack struct hold resilia engines used by local commands
+	stack struct {
+		kubeEngine    *kube.Engine
+		pumbaEngine   *pumba.Engine
+		suiteResource resouces.SuiteStore
+		suiteService  *services.Service
+	}
+)
+
+var commands = map[string]command{
+	"serve":    {run: serveCommand},
+	"run":      {run: runCommand},
+	"validate": {run: validateCommand},
+	"stop":     {run: stopCommand},
+	"list":     {run: listCommand},
+	"gc":       {run: gcCommand},
+}
+
 func main() {
+	log.SetFlags(0)
 
-	flag.BoolVar(&inCluster, "in_cluster", false, " bool flag if this app run inside k8s cluster (default false)")
-	flag.StringVar(&httpPort, "http_port", ":8181", "define http port for resilia server")
-	flag.Parse()
+	name, args := "serve", os.Args[1:]
 
-	var (
-		kubeEngine *kube.Engine
-		err        error
-	)
+	// keep old usage working, flags without command start the server
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
 
-	log.Println(inCluster)
+	if name == "help" {
+		fmt.Print(usage)
+		return
+	}
 
-	// setup k8s
-	switch inCluster {
-	case true:
-		kubeEngine, err = kube.New()
-		if err != nil {
-			log.Fatal(err)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+
+	if err := cmd.run(args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// newFlagSet function return flag set of command with shared flags bound to config
+// remote flag only bound for commands able to talk to running server
+func newFlagSet(name string, conf *config, remote bool) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	fs.BoolVar(&conf.inCluster, "in_cluster", false, "bool flag if this app run inside k8s cluster (default false)")
+	fs.StringVar(&conf.store, "store", storeRedis, "suites storage backend, one of : redis, bolt, memory")
+	fs.StringVar(&conf.redisHost, "redis_host", "localhost:6379", "define redis host for suites storage")
+	fs.StringVar(&conf.boltPath, "bolt_path", "resilia.db", "define bolt file path for suites storage")
+	fs.DurationVar(&conf.retention, "run_retention", 7*24*time.Hour, "how long finished run kept on storage, 0 keep forever, suites always kept")
+
+	if remote {
+		fs.StringVar(&conf.server, "server", "", "resilia server url, eg : http://localhost:8181, when set command sent to the server instead of run locally")
+	}
+
+	return fs
+}
+
+// newKubeEngine function return kube engine by given config
+func newKubeEngine(conf config) (*kube.Engine, error) {
+	if conf.inCluster {
+		return kube.New()
+	}
+	return kube.New(kube.WithOutsideClusterConfig())
+}
+
+// newStore function return suites storage backend by given config
+// memory store lost on exit, so it's only useful for serve or trying things out
+func newStore(conf config) (resouces.SuiteStore, error) {
+	retention := resouces.WithRunRetention(conf.retention)
+
+	switch conf.store {
+	case storeRedis:
+		return resouces.NewRedis(cache.New(conf.redisHost), retention), nil
+	case storeBolt:
+		store, err := resouces.NewBolt(conf.boltPath, retention)
+		if errors.Is(err, resouces.ErrStoreLocked) {
+			// serve keep the file locked as long as it's running
+			return nil, fmt.Errorf("%w, send the command to it with -server or use redis store", err)
 		}
-	case false:
-		kubeEngine, err = kube.New(kube.WithOutsideClusterConfig())
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
+		return store, nil
+	case storeMemory:
+		return resouces.NewMemory(retention), nil
+	}
+	return nil, fmt.Errorf("unknown store %q, expected one of : redis, bolt, memory", conf.store)
+}
+
+// newStack function setup resilia engines used to run suites locally
+func newStack(conf config) (*stack, error) {
+	kubeEngine, err := newKubeEngine(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	suiteResource, err := newStore(conf)
+	if err != nil {
+		return nil, err
 	}
 
 	pumbaEngine := pumba.New(kubeEngine)
-	suiteService := services.New(kubeEngine, pumbaEngine)
 
-	httpAPI := httpserver.New(httpPort, 5*time.Second, suiteService)
-	httpAPI.Run(httpPort)
+	return &stack{
+		kubeEngine:    kubeEngine,
+		pumbaEngine:   pumbaEngine,
+		suiteResource: suiteResource,
+		suiteService:  services.New(kubeEngine, pumbaEngine, suiteResource),
+	}, nil
+}
 
+// argument function return the only positional argument of command
+func argument(fs *flag.FlagSet, name string) (string, error) {
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("%s: expected exactly one <%s> argument", fs.Name(), name)
+	}
+	return fs.Arg(0), nil
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/resouces"
+)
+
+// reconcileStore function reconcile suites storage every given interval
+// first reconciliation run right away, so suites stored with expiry
+// by older version persisted on startup
+// meant to run on it's own goroutine along with http server
+func reconcileStore(store resouces.SuiteStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		report, err := store.Reconcile()
+		if err != nil {
+			log.Println("storage reconciliation failed: ", err)
+			continue
+		}
+
+		if len(report.DanglingSuites)+len(report.DanglingRuns)+len(report.ExpiredRuns) > 0 {
+			log.Printf("storage reconciled, removed %d dangling suite(s), %d dangling run(s), %d expired run(s)",
+				len(report.DanglingSuites), len(report.DanglingRuns), len(report.ExpiredRuns))
+		}
+	}
+}
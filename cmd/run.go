@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/engine/suites/suitefile"
+)
+
+// runCommand function run suite document until interrupted or
+// given duration passed, then tear down all of it's created resources
+func runCommand(args []string) error {
+	var (
+		conf     config
+		duration time.Duration
+		fs       = newFlagSet("run", &conf, true)
+	)
+
+	fs.DurationVar(&duration, "duration", 0, "stop the run after given duration, eg : 10m (default suite duration, or wait for ctrl-c)")
+	fs.Parse(args)
+
+	path, err := argument(fs, "suite.yaml")
+	if err != nil {
+		return err
+	}
+
+	// interrupt caught before run started, so run interrupted
+	// while being started still rolled back
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	if conf.server != "" {
+		return runRemote(newAPIClient(conf.server), path, duration, interrupt)
+	}
+
+	s, err := newStack(conf)
+	if err != nil {
+		return err
+	}
+	defer s.suiteResource.Close()
+
+	suite, err := suitefile.New(s.suiteService, s.pumbaEngine).ParseFile(path)
+	if err != nil {
+		return err
+	}
+
+	// persisted as run deadline, so run still stopped by resilia
+	// server or garbage collector when this process die
+	if duration > 0 {
+		suite.Duration = duration.String()
+	}
+
+	run, err := s.suiteService.NewRun(suite)
+	if err != nil {
+		return err
+	}
+
+	started := make(chan error, 1)
+	go func() {
+		started <- s.suiteService.StartPendingRun(suite, run)
+	}()
+
+	select {
+	case err = <-started:
+	case <-interrupt:
+		log.Println("interrupted while starting, rolling back")
+
+		// start notice the stop and remove resources it created meanwhile
+		report, stopErr := s.suiteService.StopSuites(run.ID)
+		printTeardownReport(report)
+		err = <-started
+
+		if stopErr != nil {
+			return stopErr
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("run %s: %w", run.ID, err)
+	}
+
+	printRunStarted(run.ID, run.CreatedResources)
+	waitRun(interrupt, untilDeadline(run.Deadline, 0))
+
+	report, err := s.suiteService.StopSuites(run.ID)
+	printTeardownReport(report)
+	return err
+}
+
+// runRemote function run suite document on resilia server
+// manifest path inlined since server can't read local files
+func runRemote(client *apiClient, path string, duration time.Duration, interrupt <-chan os.Signal) error {
+	document, err := suitefile.InlineFile(path)
+	if err != nil {
+		return err
+	}
+
+	type result struct {
+		run runResponse
+		err error
+	}
+
+	started := make(chan result, 1)
+	go func() {
+		run, err := client.RunSuite(document)
+		started <- result{run, err}
+	}()
+
+	var (
+		res         result
+		interrupted bool
+	)
+
+	select {
+	case res = <-started:
+	case <-interrupt:
+		// run id known only once server respond
+		log.Println("interrupted while starting, stopping once started")
+		res = <-started
+		interrupted = true
+	}
+
+	if res.err != nil {
+		var apiErr *apiError
+		if errors.As(res.err, &apiErr) && len(apiErr.Errors) > 0 {
+			return fmt.Errorf("%w\n%s", res.err, apiErr.Errors)
+		}
+		return res.err
+	}
+
+	run := res.run
+	printRunStarted(run.RunID, run.CreatedResources)
+	if !interrupted {
+		waitRun(interrupt, untilDeadline(run.Deadline, duration))
+	}
+
+	resp, err := client.StopRun(run.RunID)
+	printTeardownReport(resp.Teardown)
+	return err
+}
+
+// untilDeadline function return how long to wait for the run, shortest of
+// given duration and time left until run deadline, zero when both unset
+func untilDeadline(deadline *time.Time, duration time.Duration) time.Duration {
+	if deadline == nil {
+		return duration
+	}
+
+	left := time.Until(*deadline)
+	if left <= 0 {
+		left = time.Nanosecond
+	}
+
+	if duration > 0 && duration < left {
+		return duration
+	}
+	return left
+}
+
+// waitRun function block until interrupted or given duration passed
+// zero duration wait for interrupt only
+func waitRun(interrupt <-chan os.Signal, duration time.Duration) {
+	var timeout <-chan time.Time
+	if duration > 0 {
+		timeout = time.After(duration)
+		log.Printf("chaos running for %s, press ctrl-c to stop earlier", duration)
+	} else {
+		log.Println("chaos running, press ctrl-c to stop")
+	}
+
+	select {
+	case <-interrupt:
+		log.Println("interrupted, tearing down")
+	case <-timeout:
+		log.Println("duration passed, tearing down")
+	}
+}
+
+func printRunStarted(runID string, created map[services.KubeKind][]string) {
+	log.Printf("run %s started", runID)
+	for kind, names := range created {
+		log.Printf("  %s: %v", kind, names)
+	}
+}
+
+func printTeardownReport(report services.TeardownReport) {
+	for _, r := range report.Removed {
+		log.Printf("removed %s %s", r.Kind, r.Name)
+	}
+	for _, r := range report.Absent {
+		log.Printf("already absent %s %s", r.Kind, r.Name)
+	}
+	for _, r := range report.Failed {
+		log.Printf("failed to remove %s %s: %s", r.Kind, r.Name, r.Error)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	httpserver "github.com/faruqisan/resilia/engine/servers/http"
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/engine/suites/suitefile"
+)
+
+// serveCommand function run resilia http server until interrupted
+func serveCommand(args []string) error {
+	var (
+		conf              config
+		httpPort          string
+		gcInterval        time.Duration
+		gcOptions         services.GCOptions
+		reconcileInterval time.Duration
+		writeTimeout      time.Duration
+		fs                = newFlagSet("serve", &conf, false)
+	)
+
+	fs.StringVar(&httpPort, "http_port", ":8181", "define http port for resilia server")
+	fs.DurationVar(&gcInterval, "gc_interval", 10*time.Minute, "interval of orphaned objects garbage collection, 0 disable it")
+	fs.DurationVar(&gcOptions.MaxAge, "gc_max_age", 24*time.Hour, "unfinished run started longer than this considered expired by garbage collector, 0 never expire")
+	fs.DurationVar(&reconcileInterval, "reconcile_interval", time.Hour, "interval of suites storage reconciliation, removing expired runs and dangling entries, 0 disable it")
+	fs.DurationVar(&writeTimeout, "write_timeout", httpserver.DefaultWriteTimeout, "max duration of writing response, suite run respond once it's chaos injected so it must outlive suites' ready_timeout, 0 no timeout")
+	fs.Parse(args)
+
+	s, err := newStack(conf)
+	if err != nil {
+		return err
+	}
+	defer s.suiteResource.Close()
+
+	// keep enforcing deadline of runs started before restart
+	scheduled, err := s.suiteService.ResumeDeadlines()
+	if err != nil {
+		return err
+	}
+	log.Printf("%d run deadline(s) resumed", scheduled)
+
+	// keep target locks of unfinished runs before they expire
+	locked, err := s.suiteService.ResumeLocks()
+	if err != nil {
+		return err
+	}
+	log.Printf("%d run target lock(s) resumed", locked)
+
+	// keep pumba workers spawned before restart following their target pods
+	workers, err := s.pumbaEngine.ResumeWorkers()
+	if err != nil {
+		return err
+	}
+	log.Printf("%d pumba worker(s) resumed", workers)
+
+	if gcInterval > 0 {
+		go collectGarbage(s.suiteService, gcInterval, gcOptions)
+	}
+
+	if reconcileInterval > 0 {
+		go reconcileStore(s.suiteResource, reconcileInterval)
+	}
+
+	suiteParser := suitefile.New(s.suiteService, s.pumbaEngine)
+	httpAPI := httpserver.New(httpPort, 5*time.Second, s.suiteService, s.suiteResource, s.pumbaEngine, suiteParser,
+		httpserver.WithWriteTimeout(writeTimeout))
+	httpAPI.Run(httpPort)
+
+	return nil
+}
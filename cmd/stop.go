@@ -0,0 +1,32 @@
+package main
+
+// stopCommand function stop suite run and delete it's created resources
+func stopCommand(args []string) error {
+	var (
+		conf config
+		fs   = newFlagSet("stop", &conf, true)
+	)
+
+	fs.Parse(args)
+
+	runID, err := argument(fs, "run-id")
+	if err != nil {
+		return err
+	}
+
+	if conf.server != "" {
+		resp, err := newAPIClient(conf.server).StopRun(runID)
+		printTeardownReport(resp.Teardown)
+		return err
+	}
+
+	s, err := newStack(conf)
+	if err != nil {
+		return err
+	}
+	defer s.suiteResource.Close()
+
+	report, err := s.suiteService.StopSuites(runID)
+	printTeardownReport(report)
+	return err
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/engine/suites/suitefile"
+	"github.com/faruqisan/resilia/pkg/kube"
+	"github.com/faruqisan/resilia/pkg/pumba"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// offlineKube struct is kube engine without cluster access used by validate
+// it only decode manifests, call of cluster method panic on nil embedded engine
+type offlineKube struct {
+	services.KubeEngine
+}
+
+func (offlineKube) LoadObjectsFromFile(file []byte) ([]runtime.Object, error) {
+	return kube.DecodeObjects(file)
+}
+
+// validateCommand function check suite document, manifests only decoded
+// so validation doesn't need cluster access
+func validateCommand(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Parse(args)
+
+	path, err := argument(fs, "suite.yaml")
+	if err != nil {
+		return err
+	}
+
+	// engines without cluster access, only used to decode manifest and build workers
+	pumbaEngine := pumba.New(nil)
+	suiteService := services.New(offlineKube{}, pumbaEngine, nil)
+
+	suite, err := suitefile.New(suiteService, pumbaEngine).ParseFile(path)
+	if err != nil {
+		var parseErr *suitefile.ParseError
+		if !errors.As(err, &parseErr) {
+			return err
+		}
+
+		for _, e := range parseErr.Errors {
+			log.Printf("%s: %s", path, e)
+		}
+		return fmt.Errorf("%s: %d error(s) found", path, len(parseErr.Errors))
+	}
+
+	log.Printf("%s: suite %q is valid, %d resource(s), %d pumba worker(s)", path, suite.Name, len(suite.Resources), len(suite.PumbaWorkers))
+	return nil
+}
@@ -9,7 +9,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/faruqisan/resilia/engine/suites/resouces"
 	suites "github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/pkg/pumba"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,40 +20,95 @@ type (
 	// SuitesService interface define contract with suite service
 	SuitesService interface {
 		NewModel(id string, name string, resources []suites.FileResource) *suites.Model
-		RunSuiteFileResources(suite *suites.Model) error
-		RunSuitePumbaWorkers(suite *suites.Model) error
-		StopSuites(suite *suites.Model) error
+		StartRun(suite *suites.Model) (*suites.Run, error)
+		FindRun(id string) (suites.Run, error)
+		GetRuns() ([]suites.Run, error)
+		StopSuites(runID string) (suites.TeardownReport, error)
 	}
 
+	// SuitesResource interface define contract with suite resource
+	// used to manage stored suites
+	SuitesResource interface {
+		Get() ([]suites.Model, error)
+		Find(id string) (suites.Model, error)
+		Update(m suites.Model) error
+		Delete(id string) error
+		CreateResource(suiteID string, resource suites.FileResource) (string, error)
+		GetSuiteResources(suiteID string) ([]suites.FileResource, error)
+		DeleteResource(suiteID, resourceID string) error
+		DeleteSuiteResources(suiteID string) error
+		Atomic(fn func(uow resouces.UnitOfWork) error) error
+	}
+
+	// PumbaEngine interface define contract with pumba engine
+	// used to build and validate pumba worker from request
+	PumbaEngine interface {
+		NewPumbaWorkerFromSpec(spec pumba.WorkerSpec) pumba.Worker
+	}
+
+	// SuiteParser interface define contract with suite document parser
+	// used to accept declarative suite document as request body
+	SuiteParser interface {
+		Parse(data []byte) (*suites.Model, error)
+	}
+
+	// Option type used to customize http server engine
+	Option func(*Engine)
+
 	// Engine struct hold http server engine required data
 	Engine struct {
-		port    string
-		timeout time.Duration
-		router  *gin.Engine
+		port         string
+		timeout      time.Duration
+		writeTimeout time.Duration
+		router       *gin.Engine
 
-		suiteService SuitesService
+		suiteService  SuitesService
+		suiteResource SuitesResource
+		pumbaEngine   PumbaEngine
+		suiteParser   SuiteParser
 	}
 )
 
+// DefaultWriteTimeout is default max duration of writing response, suite run
+// respond once it's chaos injected, so it must outlive suite ready timeout
+const DefaultWriteTimeout = 10 * time.Minute
+
 // New function return setuped http server engine
-func New(port string, timeout time.Duration, suitesService SuitesService) *Engine {
+func New(port string, timeout time.Duration, suitesService SuitesService, suitesResource SuitesResource, pumbaEngine PumbaEngine, suiteParser SuiteParser, opts ...Option) *Engine {
 	e := &Engine{
-		port:         port,
-		timeout:      timeout,
-		router:       gin.Default(),
-		suiteService: suitesService,
+		port:          port,
+		timeout:       timeout,
+		writeTimeout:  DefaultWriteTimeout,
+		router:        gin.Default(),
+		suiteService:  suitesService,
+		suiteResource: suitesResource,
+		pumbaEngine:   pumbaEngine,
+		suiteParser:   suiteParser,
+	}
+
+	for _, opt := range opts {
+		opt(e)
 	}
 
 	e.initRoutes()
 	return e
 }
 
+// WithWriteTimeout function set max duration of writing response
+// zero timeout never cut response off
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(e *Engine) {
+		e.writeTimeout = timeout
+	}
+}
+
 // Run function run new http server
 func (e *Engine) Run(port string) {
 
 	apiServer := &http.Server{
-		Addr:    e.port,
-		Handler: e.router,
+		Addr:         e.port,
+		Handler:      e.router,
+		WriteTimeout: e.writeTimeout,
 	}
 
 	go func() {
@@ -21,5 +21,23 @@ func (e *Engine) initRoutes() {
 	suites := e.router.Group("/suites")
 	{
 		suites.POST("/", e.HandlerSuiteRun)
+		suites.GET("/", e.HandlerSuiteList)
+		suites.GET("/:id", e.HandlerSuiteFind)
+		suites.PUT("/:id", e.HandlerSuiteUpdate)
+		suites.DELETE("/:id", e.HandlerSuiteDelete)
+
+		suites.POST("/:id/resources", e.HandlerSuiteResourceCreate)
+		suites.GET("/:id/resources", e.HandlerSuiteResourceList)
+		suites.DELETE("/:id/resources", e.HandlerSuiteResourceDeleteAll)
+		suites.DELETE("/:id/resources/:resource_id", e.HandlerSuiteResourceDelete)
+
+		suites.POST("/:id/runs", e.HandlerSuiteRunStored)
+	}
+
+	runs := e.router.Group("/runs")
+	{
+		runs.GET("/", e.HandlerRunList)
+		runs.GET("/:id", e.HandlerRunFind)
+		runs.POST("/:id/stop", e.HandlerRunStop)
 	}
 }
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	suites "github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/gin-gonic/gin"
+)
+
+// runStopResponse struct define response body of stopped run
+type runStopResponse struct {
+	Run      suites.Run            `json:"run"`
+	Teardown suites.TeardownReport `json:"teardown"`
+}
+
+// HandlerRunList handle to list all suite runs
+func (e *Engine) HandlerRunList(c *gin.Context) {
+	runs, err := e.suiteService.GetRuns()
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// HandlerRunFind handle to get single suite run
+func (e *Engine) HandlerRunFind(c *gin.Context) {
+	run, err := e.suiteService.FindRun(c.Param("id"))
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// HandlerRunStop handle to stop suite run and terminate it's created resources
+func (e *Engine) HandlerRunStop(c *gin.Context) {
+	id := c.Param("id")
+
+	report, err := e.suiteService.StopSuites(id)
+
+	var teardownErr *suites.TeardownError
+	if err != nil && !errors.As(err, &teardownErr) {
+		abortWithSuiteError(c, err)
+		return
+	}
+
+	run, findErr := e.suiteService.FindRun(id)
+	if findErr != nil {
+		abortWithResourceError(c, findErr)
+		return
+	}
+
+	status := http.StatusOK
+	if teardownErr != nil {
+		status = http.StatusBadGateway
+	}
+
+	c.JSON(status, runStopResponse{
+		Run:      run,
+		Teardown: report,
+	})
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"net/http"
+
+	suites "github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/gin-gonic/gin"
+)
+
+// HandlerSuiteResourceCreate handle to append file resource to stored suite
+func (e *Engine) HandlerSuiteResourceCreate(c *gin.Context) {
+	var (
+		id       = c.Param("id")
+		resource suites.FileResource
+	)
+
+	if err := c.ShouldBindJSON(&resource); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateFileResources([]suites.FileResource{resource}); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, err := e.suiteResource.Find(id); err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	resourceID, err := e.suiteResource.CreateResource(id, resource)
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	resource.ID = resourceID
+	resource.SuiteID = id
+
+	c.JSON(http.StatusCreated, resource)
+}
+
+// HandlerSuiteResourceList handle to list file resources of stored suite
+func (e *Engine) HandlerSuiteResourceList(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := e.suiteResource.Find(id); err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	resources, err := e.suiteResource.GetSuiteResources(id)
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	if resources == nil {
+		resources = []suites.FileResource{}
+	}
+
+	c.JSON(http.StatusOK, resources)
+}
+
+// HandlerSuiteResourceDeleteAll handle to remove all file resources of stored suite
+func (e *Engine) HandlerSuiteResourceDeleteAll(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := e.suiteResource.Find(id); err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	if err := e.suiteResource.DeleteSuiteResources(id); err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandlerSuiteResourceDelete handle to remove single file resource of stored suite
+func (e *Engine) HandlerSuiteResourceDelete(c *gin.Context) {
+	if err := e.suiteResource.DeleteResource(c.Param("id"), c.Param("resource_id")); err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -1,8 +1,392 @@
 package http
 
-import "github.com/gin-gonic/gin"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
 
-// HandlerSuiteRun handle to run suite
+	"github.com/faruqisan/resilia/engine/suites/resouces"
+	suites "github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/engine/suites/suitefile"
+	"github.com/faruqisan/resilia/pkg/pumba"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type (
+	// suiteRunRequest struct define request body to run suite
+	suiteRunRequest struct {
+		Name         string                `json:"name" binding:"required"`
+		Resources    []suites.FileResource `json:"resources"`
+		PumbaWorkers []pumba.WorkerSpec    `json:"pumba_workers"`
+
+		// DisableRollback keep created resources when run failed, for debugging
+		DisableRollback bool `json:"disable_rollback"`
+		// ReadyTimeout max duration to wait applied workloads ready, eg : 5m
+		ReadyTimeout string `json:"ready_timeout"`
+		// Duration max duration of the run, run stopped automatically once passed, eg : 30m
+		Duration string `json:"duration"`
+	}
+
+	// suiteRunResponse struct define response body of suite run
+	suiteRunResponse struct {
+		RunID            string                       `json:"run_id"`
+		SuiteID          string                       `json:"suite_id"`
+		Status           suites.RunStatus             `json:"status"`
+		Deadline         *time.Time                   `json:"deadline,omitempty"`
+		CreatedResources map[suites.KubeKind][]string `json:"created_resources"`
+	}
+)
+
+// suiteDocumentContentTypes define content types of request body
+// parsed as declarative suite document
+var suiteDocumentContentTypes = map[string]bool{
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+}
+
+// isSuiteDocument function report whether request body is suite document
+// yaml body always is, json body is when it declare document version
+func isSuiteDocument(c *gin.Context) bool {
+	if suiteDocumentContentTypes[c.ContentType()] {
+		return true
+	}
+
+	data, err := c.GetRawData()
+	if err != nil {
+		return false
+	}
+	// body read again by json binding or document parser
+	c.Request.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	var doc struct {
+		Version string `json:"version"`
+	}
+
+	return json.Unmarshal(data, &doc) == nil && doc.Version != ""
+}
+
+// HandlerSuiteRun handle to run suite from json request
+// or from suite document when sent as yaml or json with version
 func (e *Engine) HandlerSuiteRun(c *gin.Context) {
-	
+	var req suiteRunRequest
+
+	if isSuiteDocument(c) {
+		suite, ok := e.parseSuiteDocument(c)
+		if !ok {
+			return
+		}
+
+		e.startRun(c, suite)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateFileResources(req.Resources); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !e.validatePumbaWorkers(c, req.PumbaWorkers) {
+		return
+	}
+
+	suite := e.suiteService.NewModel(uuid.New().String(), req.Name, req.Resources)
+	suite.PumbaWorkers = req.PumbaWorkers
+	suite.DisableRollback = req.DisableRollback
+	suite.ReadyTimeout = req.ReadyTimeout
+	suite.Duration = req.Duration
+
+	e.startRun(c, suite)
+}
+
+// HandlerSuiteRunStored handle to run stored suite with it's resources
+func (e *Engine) HandlerSuiteRunStored(c *gin.Context) {
+	id := c.Param("id")
+
+	stored, err := e.suiteResource.Find(id)
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	resources, err := e.suiteResource.GetSuiteResources(id)
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	suite := e.suiteService.NewModel(stored.ID, stored.Name, resources)
+	suite.PumbaWorkers = stored.PumbaWorkers
+	suite.DisableRollback = stored.DisableRollback
+	suite.ReadyTimeout = stored.ReadyTimeout
+	suite.Duration = stored.Duration
+
+	e.startRun(c, suite)
+}
+
+func (e *Engine) startRun(c *gin.Context, suite *suites.Model) {
+	run, err := e.suiteService.StartRun(suite)
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+		if run != nil {
+			body["run_id"] = run.ID
+		}
+
+		// tell who to talk to before retrying
+		var lockedErr *suites.TargetLockedError
+		if errors.As(err, &lockedErr) {
+			body["target"] = lockedErr.Target
+			body["holder_run_id"] = lockedErr.HolderRunID
+		}
+
+		c.AbortWithStatusJSON(suiteErrorStatus(err), body)
+		return
+	}
+
+	c.JSON(http.StatusCreated, suiteRunResponse{
+		RunID:            run.ID,
+		SuiteID:          run.SuiteID,
+		Status:           run.Status,
+		Deadline:         run.Deadline,
+		CreatedResources: run.CreatedResources,
+	})
+}
+
+// newPumbaWorker function build and validate pumba worker from spec
+func (e *Engine) newPumbaWorker(spec pumba.WorkerSpec) (pumba.Worker, error) {
+	worker := e.pumbaEngine.NewPumbaWorkerFromSpec(spec)
+	return worker, worker.Validate()
+}
+
+// validatePumbaWorkers function validate every worker spec
+// aborting request with field level errors of first invalid worker
+func (e *Engine) validatePumbaWorkers(c *gin.Context, specs []pumba.WorkerSpec) bool {
+	for i, spec := range specs {
+		if _, err := e.newPumbaWorker(spec); err != nil {
+			abortWithWorkerError(c, i, err)
+			return false
+		}
+	}
+	return true
+}
+
+// parseSuiteDocument function parse request body as suite document
+// aborting request with document's schema errors when it's invalid
+func (e *Engine) parseSuiteDocument(c *gin.Context) (*suites.Model, bool) {
+	data, err := c.GetRawData()
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	suite, err := e.suiteParser.Parse(data)
+	if err != nil {
+		body := gin.H{"error": err.Error()}
+
+		var parseErr *suitefile.ParseError
+		if errors.As(err, &parseErr) {
+			body["errors"] = parseErr.Errors
+		}
+
+		c.AbortWithStatusJSON(http.StatusBadRequest, body)
+		return nil, false
+	}
+
+	return suite, true
+}
+
+// abortWithWorkerError abort request with worker's field level errors
+func abortWithWorkerError(c *gin.Context, index int, err error) {
+	var (
+		validationErr *pumba.ValidationError
+		prefix        = fmt.Sprintf("pumba_workers[%d]", index)
+	)
+
+	if !errors.As(err, &validationErr) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: %s", prefix, err)})
+		return
+	}
+
+	fields := make([]pumba.FieldError, 0, len(validationErr.Errors))
+	for _, f := range validationErr.Errors {
+		fields = append(fields, pumba.FieldError{
+			Field:   prefix + "." + f.Field,
+			Message: f.Message,
+		})
+	}
+
+	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+		"error":  fmt.Sprintf("%s: %s", prefix, err),
+		"fields": fields,
+	})
+}
+
+func validateFileResources(resources []suites.FileResource) error {
+	for i, resource := range resources {
+		switch resource.Kind {
+		case suites.KindDeployment, suites.KindService, suites.KindDaemonSet, suites.KindManifest:
+		default:
+			return fmt.Errorf("resources[%d]: unknown kind %q", i, resource.Kind)
+		}
+
+		if resource.Value == "" {
+			return fmt.Errorf("resources[%d]: value is required", i)
+		}
+	}
+	return nil
+}
+
+// abortWithSuiteError abort request with status based on suite service error
+func abortWithSuiteError(c *gin.Context, err error) {
+	c.AbortWithStatusJSON(suiteErrorStatus(err), gin.H{"error": err.Error()})
+}
+
+// suiteErrorStatus return http status of suite service error
+// invalid resource is client fault, otherwise it's cluster or store failure
+func suiteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, suites.ErrInvalidResource), errors.Is(err, suites.ErrInvalidSuite):
+		return http.StatusBadRequest
+	case errors.Is(err, resouces.ErrRunNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, suites.ErrTargetLocked), errors.Is(err, suites.ErrRunStopped), errors.Is(err, suites.ErrLockLost):
+		return http.StatusConflict
+	case errors.Is(err, resouces.ErrStoreUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadGateway
+}
+
+// suiteUpdateRequest struct define request body to update suite
+type suiteUpdateRequest struct {
+	Name         string             `json:"name" binding:"required"`
+	PumbaWorkers []pumba.WorkerSpec `json:"pumba_workers"`
+
+	DisableRollback bool   `json:"disable_rollback"`
+	ReadyTimeout    string `json:"ready_timeout"`
+	Duration        string `json:"duration"`
+}
+
+// HandlerSuiteList handle to list all stored suites
+func (e *Engine) HandlerSuiteList(c *gin.Context) {
+	suites, err := e.suiteResource.Get()
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suites)
+}
+
+// HandlerSuiteFind handle to get stored suite with it's resources
+func (e *Engine) HandlerSuiteFind(c *gin.Context) {
+	id := c.Param("id")
+
+	suite, err := e.suiteResource.Find(id)
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	suite.Resources, err = e.suiteResource.GetSuiteResources(id)
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suite)
+}
+
+// HandlerSuiteUpdate handle to create or replace stored suite
+// suite document sent as yaml or json replace stored suite's resources too
+func (e *Engine) HandlerSuiteUpdate(c *gin.Context) {
+	var req suiteUpdateRequest
+
+	if isSuiteDocument(c) {
+		e.updateSuiteFromDocument(c)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !e.validatePumbaWorkers(c, req.PumbaWorkers) {
+		return
+	}
+
+	suite := suites.Model{
+		ID:              c.Param("id"),
+		Name:            req.Name,
+		PumbaWorkers:    req.PumbaWorkers,
+		DisableRollback: req.DisableRollback,
+		ReadyTimeout:    req.ReadyTimeout,
+		Duration:        req.Duration,
+	}
+
+	if err := e.suiteResource.Update(suite); err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suite)
+}
+
+// updateSuiteFromDocument function store suite document under requested id
+// replacing stored suite and all of it's resources at once
+func (e *Engine) updateSuiteFromDocument(c *gin.Context) {
+	suite, ok := e.parseSuiteDocument(c)
+	if !ok {
+		return
+	}
+	suite.ID = c.Param("id")
+
+	err := e.suiteResource.Atomic(func(uow resouces.UnitOfWork) error {
+		uow.Update(*suite)
+		uow.DeleteSuiteResources(suite.ID)
+		for i, resource := range suite.Resources {
+			suite.Resources[i].ID = uow.CreateResource(suite.ID, resource)
+			suite.Resources[i].SuiteID = suite.ID
+		}
+		return nil
+	})
+	if err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suite)
+}
+
+// HandlerSuiteDelete handle to delete stored suite
+func (e *Engine) HandlerSuiteDelete(c *gin.Context) {
+	if err := e.suiteResource.Delete(c.Param("id")); err != nil {
+		abortWithResourceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// abortWithResourceError abort request with status based on suite resource error
+func abortWithResourceError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, resouces.ErrSuiteNotFound), errors.Is(err, resouces.ErrResourceNotFound), errors.Is(err, resouces.ErrRunNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, resouces.ErrStoreUnavailable):
+		status = http.StatusServiceUnavailable
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
 }
@@ -0,0 +1,251 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/resouces"
+	suites "github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/pkg/pumba"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// fakeSuitesService struct is suite service returning preset result
+	// call of method not implemented here panic on nil embedded service
+	fakeSuitesService struct {
+		SuitesService
+
+		run      *suites.Run
+		startErr error
+		report   suites.TeardownReport
+		stopErr  error
+	}
+
+	// fakeSuitesResource struct is suite resource holding no suite
+	fakeSuitesResource struct {
+		SuitesResource
+		err error
+	}
+)
+
+func (s *fakeSuitesService) NewModel(id string, name string, resources []suites.FileResource) *suites.Model {
+	return &suites.Model{ID: id, Name: name, Resources: resources}
+}
+
+func (s *fakeSuitesService) StartRun(suite *suites.Model) (*suites.Run, error) {
+	return s.run, s.startErr
+}
+
+func (s *fakeSuitesService) StopSuites(runID string) (suites.TeardownReport, error) {
+	return s.report, s.stopErr
+}
+
+func (s *fakeSuitesService) FindRun(id string) (suites.Run, error) {
+	if s.run == nil || s.run.ID != id {
+		return suites.Run{}, resouces.ErrRunNotFound
+	}
+	return *s.run, nil
+}
+
+func (r *fakeSuitesResource) Find(id string) (suites.Model, error) {
+	return suites.Model{}, r.err
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newTestEngine(svc *fakeSuitesService, res *fakeSuitesResource) *Engine {
+	return New(":0", time.Second, svc, res, pumba.New(nil), nil)
+}
+
+func serve(e *Engine, method, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+func TestHandlerSuiteRunStatus(t *testing.T) {
+	run := &suites.Run{ID: "run-1", SuiteID: "suite-1", Status: suites.RunStatusFailed}
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		// body fields expected on response beside error
+		body map[string]interface{}
+	}{
+		{
+			name:   "started",
+			status: http.StatusCreated,
+			body:   map[string]interface{}{"run_id": "run-1", "suite_id": "suite-1"},
+		},
+		{
+			name:   "invalid suite",
+			err:    fmt.Errorf("%w: duration: must be positive", suites.ErrInvalidSuite),
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid resource",
+			err:    fmt.Errorf("%w: unknown kind", suites.ErrInvalidResource),
+			status: http.StatusBadRequest,
+			body:   map[string]interface{}{"run_id": "run-1"},
+		},
+		{
+			name:   "run not found",
+			err:    resouces.ErrRunNotFound,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "target locked",
+			err:    &suites.RollbackError{Cause: &suites.TargetLockedError{Target: "default/deployment/redis", HolderRunID: "run-0"}},
+			status: http.StatusConflict,
+			body: map[string]interface{}{
+				"run_id":        "run-1",
+				"target":        "default/deployment/redis",
+				"holder_run_id": "run-0",
+			},
+		},
+		{
+			name:   "run stopped",
+			err:    suites.ErrRunStopped,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "store unavailable",
+			err:    &resouces.StoreError{Op: "save run", Err: errors.New("connection refused")},
+			status: http.StatusServiceUnavailable,
+		},
+		{
+			name:   "cluster failure",
+			err:    errors.New("deployments.apps is forbidden"),
+			status: http.StatusBadGateway,
+			body:   map[string]interface{}{"run_id": "run-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeSuitesService{startErr: tt.err}
+			// invalid suite rejected before run created
+			if !errors.Is(tt.err, suites.ErrInvalidSuite) {
+				svc.run = run
+			}
+
+			rec, resp := serve(newTestEngine(svc, &fakeSuitesResource{}), http.MethodPost, "/suites/", `{"name":"redis"}`)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, tt.status, rec.Body)
+			}
+
+			if tt.err != nil && resp["error"] != tt.err.Error() {
+				t.Errorf("error = %v, want %q", resp["error"], tt.err)
+			}
+
+			for key, want := range tt.body {
+				if resp[key] != want {
+					t.Errorf("%s = %v, want %v", key, resp[key], want)
+				}
+			}
+
+			if _, ok := resp["holder_run_id"]; ok && tt.body["holder_run_id"] == nil {
+				t.Errorf("holder_run_id = %v, want none", resp["holder_run_id"])
+			}
+		})
+	}
+}
+
+func TestHandlerSuiteRunInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing name", `{}`},
+		{"unknown resource kind", `{"name":"redis","resources":[{"kind":"pod","value":"{}"}]}`},
+		{"invalid worker", `{"name":"redis","pumba_workers":[{"target":{"deployment":"redis"}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// service never asked to start invalid run
+			svc := &fakeSuitesService{startErr: errors.New("must not be called")}
+
+			rec, _ := serve(newTestEngine(svc, &fakeSuitesResource{}), http.MethodPost, "/suites/", tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d, body = %s", rec.Code, http.StatusBadRequest, rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandlerSuiteRunStoredStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"suite not found", resouces.ErrSuiteNotFound, http.StatusNotFound},
+		{"store unavailable", &resouces.StoreError{Op: "find suite", Err: errors.New("i/o timeout")}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEngine(&fakeSuitesService{}, &fakeSuitesResource{err: tt.err})
+
+			rec, _ := serve(e, http.MethodPost, "/suites/missing/runs", "")
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d, body = %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestHandlerRunStopStatus(t *testing.T) {
+	run := &suites.Run{ID: "run-1", Status: suites.RunStatusFailed}
+	failed := suites.TeardownReport{Failed: []suites.FailedResource{{
+		CreatedResource: suites.CreatedResource{Kind: suites.KindDeployment, Name: "redis"},
+		Error:           "forbidden",
+	}}}
+
+	tests := []struct {
+		name   string
+		run    *suites.Run
+		report suites.TeardownReport
+		err    error
+		status int
+	}{
+		{"stopped", run, suites.TeardownReport{}, nil, http.StatusOK},
+		{"teardown failed", run, failed, &suites.TeardownError{Report: failed}, http.StatusBadGateway},
+		{"run not found", nil, suites.TeardownReport{}, resouces.ErrRunNotFound, http.StatusNotFound},
+		{"store unavailable", run, suites.TeardownReport{}, &resouces.StoreError{Op: "find run", Err: errors.New("eof")}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeSuitesService{run: tt.run, report: tt.report, stopErr: tt.err}
+
+			rec, resp := serve(newTestEngine(svc, &fakeSuitesResource{}), http.MethodPost, "/runs/run-1/stop", "")
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, tt.status, rec.Body)
+			}
+
+			// teardown report still returned when some resources failed to be removed
+			if tt.status == http.StatusBadGateway && resp["teardown"] == nil {
+				t.Errorf("teardown report missing, body = %s", rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,678 @@
+package resouces
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketSuites              = []byte("suites")                // suite id -> suite
+	bucketSuiteResources      = []byte("suite_resources")       // suite id -> bucket of sequence -> file resource
+	bucketRuns                = []byte("runs")                  // run id -> run
+	bucketRunCreatedResources = []byte("run_created_resources") // run id -> bucket of kind/name -> kind
+	bucketLocks               = []byte("locks")                 // lock target -> lock record
+	bucketLockTokens          = []byte("lock_tokens")           // lock target -> last fencing token
+	bucketMeta                = []byte("meta")                  // store metadata, eg: store id
+	boltBuckets               = [][]byte{bucketSuites, bucketSuiteResources, bucketRuns, bucketRunCreatedResources, bucketLocks, bucketLockTokens, bucketMeta}
+	boltKeyStoreID            = []byte("store_id")
+	boltOpenTimeout           = 5 * time.Second
+)
+
+type (
+	// BoltStore struct is suite store backed by embedded bolt file
+	// meant for single node deployment without redis
+	BoltStore struct {
+		options
+		id string
+		db *bolt.DB
+	}
+
+	// boltUnitOfWork struct write directly on bolt read write transaction
+	// the transaction rolled back when any write failed
+	boltUnitOfWork struct {
+		tx  *bolt.Tx
+		err error // first error of the writes
+	}
+)
+
+// NewBolt function open (or create) bolt file on given path as suite store
+// the file locked while store open, so only one process able to use it
+// finished runs older than run retention removed on reconcile
+func NewBolt(path string, opts ...Option) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: boltOpenTimeout})
+	if err == bolt.ErrTimeout {
+		return nil, unavailable("open", fmt.Errorf("%w: %s", ErrStoreLocked, path))
+	}
+	if err != nil {
+		return nil, unavailable("open", err)
+	}
+
+	var id string
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range boltBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		// store id generated once, kept along with the data
+		meta := tx.Bucket(bucketMeta)
+		if raw := meta.Get(boltKeyStoreID); raw != nil {
+			id = string(raw)
+			return nil
+		}
+		id = uuid.New().String()
+		return meta.Put(boltKeyStoreID, []byte(id))
+	})
+	if err != nil {
+		db.Close()
+		return nil, unavailable("open", err)
+	}
+
+	return &BoltStore{options: newOptions(opts), id: id, db: db}, nil
+}
+
+// StoreID function return id of the store, generated when bolt file created
+func (e *BoltStore) StoreID() (string, error) {
+	return e.id, nil
+}
+
+// Close function close bolt file
+func (e *BoltStore) Close() error {
+	return e.db.Close()
+}
+
+// view function run given read only transaction function
+// failure of bolt itself returned as store error of given operation
+func (e *BoltStore) view(op string, fn func(*bolt.Tx) error) error {
+	return boltError(op, e.db.View(fn))
+}
+
+// update function run given read write transaction function
+// failure of bolt itself returned as store error of given operation
+func (e *BoltStore) update(op string, fn func(*bolt.Tx) error) error {
+	return boltError(op, e.db.Update(fn))
+}
+
+// boltError function return error of transaction of given operation
+// store's own error returned as is, eg: not found or undecodable record,
+// anything else is failure of bolt itself, eg: failed write or commit
+func boltError(op string, err error) error {
+	var (
+		storeErr  *StoreError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+
+	switch {
+	case err == nil,
+		errors.Is(err, ErrSuiteNotFound),
+		errors.Is(err, ErrRunNotFound),
+		errors.Is(err, ErrResourceNotFound),
+		errors.Is(err, services.ErrTargetLocked),
+		errors.Is(err, services.ErrLockLost),
+		errors.As(err, &storeErr),
+		errors.As(err, &syntaxErr),
+		errors.As(err, &typeErr):
+		return err
+	}
+
+	return unavailable(op, err)
+}
+
+// suiteExist function return ErrSuiteNotFound when suite with given id doesn't exist
+func suiteExist(tx *bolt.Tx, id string) error {
+	if tx.Bucket(bucketSuites).Get([]byte(id)) == nil {
+		return ErrSuiteNotFound
+	}
+	return nil
+}
+
+// Create function store new suite with given name returning id of model
+func (e *BoltStore) Create(name string) (string, error) {
+	m := services.Model{
+		ID:   uuid.New().String(),
+		Name: name,
+	}
+
+	return m.ID, e.Update(m)
+}
+
+// Get function return all suites on store
+func (e *BoltStore) Get() ([]services.Model, error) {
+	suites := []services.Model{}
+
+	err := e.view("get suites", func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketSuites).ForEach(func(_, raw []byte) error {
+			var m services.Model
+			if err := json.Unmarshal(raw, &m); err != nil {
+				return err
+			}
+			suites = append(suites, m)
+			return nil
+		})
+	})
+
+	return suites, err
+}
+
+// Find function return suite model from given id
+func (e *BoltStore) Find(id string) (services.Model, error) {
+	var m services.Model
+
+	err := e.view("find suite", func(tx *bolt.Tx) error {
+		raw := tx.Bucket(bucketSuites).Get([]byte(id))
+		if raw == nil {
+			return ErrSuiteNotFound
+		}
+		return json.Unmarshal(raw, &m)
+	})
+
+	return m, err
+}
+
+// Update function store given suite model under it's id
+// suite will be created when it doesn't exist yet
+func (e *BoltStore) Update(m services.Model) error {
+	return e.atomic("update suite", func(uow UnitOfWork) error {
+		uow.Update(m)
+		return nil
+	})
+}
+
+// Delete function remove suite and it's resources from store
+func (e *BoltStore) Delete(id string) error {
+	return e.update("delete suite", func(tx *bolt.Tx) error {
+		var (
+			suites    = tx.Bucket(bucketSuites)
+			resources = tx.Bucket(bucketSuiteResources)
+			key       = []byte(id)
+		)
+
+		hasSuite := suites.Get(key) != nil
+		hasResources := resources.Bucket(key) != nil
+		if !hasSuite && !hasResources {
+			return ErrSuiteNotFound
+		}
+
+		if hasResources {
+			if err := resources.DeleteBucket(key); err != nil {
+				return err
+			}
+		}
+
+		return suites.Delete(key)
+	})
+}
+
+// CreateResource function append file resource to suite
+// returning id of created resource
+func (e *BoltStore) CreateResource(suiteID string, resource services.FileResource) (string, error) {
+	var id string
+
+	err := e.update("create resource", func(tx *bolt.Tx) error {
+		if err := suiteExist(tx, suiteID); err != nil {
+			return err
+		}
+
+		uow := &boltUnitOfWork{tx: tx}
+		id = uow.CreateResource(suiteID, resource)
+		return uow.err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// GetSuiteResources function return suite resources by creation order
+func (e *BoltStore) GetSuiteResources(suiteID string) ([]services.FileResource, error) {
+	var resources []services.FileResource
+
+	err := e.view("get suite resources", func(tx *bolt.Tx) error {
+		if err := suiteExist(tx, suiteID); err != nil {
+			return err
+		}
+
+		b := tx.Bucket(bucketSuiteResources).Bucket([]byte(suiteID))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(_, raw []byte) error {
+			var r services.FileResource
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return err
+			}
+			resources = append(resources, r)
+			return nil
+		})
+	})
+
+	return resources, err
+}
+
+// DeleteResource function remove file resource with given id from suite
+func (e *BoltStore) DeleteResource(suiteID, resourceID string) error {
+	return e.update("delete resource", func(tx *bolt.Tx) error {
+		if err := suiteExist(tx, suiteID); err != nil {
+			return err
+		}
+
+		b := tx.Bucket(bucketSuiteResources).Bucket([]byte(suiteID))
+		if b == nil {
+			return ErrResourceNotFound
+		}
+
+		c := b.Cursor()
+		for k, raw := c.First(); k != nil; k, raw = c.Next() {
+			var r services.FileResource
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return err
+			}
+
+			if r.ID == resourceID {
+				return c.Delete()
+			}
+		}
+
+		return ErrResourceNotFound
+	})
+}
+
+// DeleteSuiteResources function remove all file resources of suite
+func (e *BoltStore) DeleteSuiteResources(suiteID string) error {
+	return e.update("delete suite resources", func(tx *bolt.Tx) error {
+		if err := suiteExist(tx, suiteID); err != nil {
+			return err
+		}
+
+		uow := &boltUnitOfWork{tx: tx}
+		uow.DeleteSuiteResources(suiteID)
+		return uow.err
+	})
+}
+
+// SaveRun function store given run under it's id
+func (e *BoltStore) SaveRun(run services.Run) error {
+	return e.atomic("save run", func(uow UnitOfWork) error {
+		uow.SaveRun(run)
+		return nil
+	})
+}
+
+// FindRun function return run from given id
+// including all of it's created resources
+func (e *BoltStore) FindRun(id string) (services.Run, error) {
+	var run services.Run
+
+	err := e.view("find run", func(tx *bolt.Tx) error {
+		raw := tx.Bucket(bucketRuns).Get([]byte(id))
+		if raw == nil {
+			return ErrRunNotFound
+		}
+
+		if err := json.Unmarshal(raw, &run); err != nil {
+			return err
+		}
+
+		// created resource appended one by one while run in progress
+		// so it's always more complete than the stored run
+		if cr := createdResources(tx, id); len(cr) > 0 {
+			run.CreatedResources = cr
+		}
+		return nil
+	})
+
+	return run, err
+}
+
+// GetRuns function return all runs on store
+func (e *BoltStore) GetRuns() ([]services.Run, error) {
+	runs := []services.Run{}
+
+	err := e.view("get runs", func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketRuns).ForEach(func(_, raw []byte) error {
+			var run services.Run
+			if err := json.Unmarshal(raw, &run); err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			return nil
+		})
+	})
+
+	return runs, err
+}
+
+// AppendCreatedResource function record created k8s resource name of run
+func (e *BoltStore) AppendCreatedResource(runID string, kind services.KubeKind, resourceName string) error {
+	return e.atomic("append created resource", func(uow UnitOfWork) error {
+		uow.AppendCreatedResource(runID, kind, resourceName)
+		return nil
+	})
+}
+
+// GetRunCreatedResources function return run's created resource
+func (e *BoltStore) GetRunCreatedResources(runID string) (map[services.KubeKind][]string, error) {
+	var cr map[services.KubeKind][]string
+
+	err := e.view("get run created resources", func(tx *bolt.Tx) error {
+		cr = createdResources(tx, runID)
+		return nil
+	})
+
+	return cr, err
+}
+
+// Reconcile function remove finished runs older than run retention
+// and created resources of missing run
+func (e *BoltStore) Reconcile() (ReconcileReport, error) {
+	var (
+		report ReconcileReport
+		now    = time.Now()
+	)
+
+	err := e.update("reconcile", func(tx *bolt.Tx) error {
+		var (
+			runs    = tx.Bucket(bucketRuns)
+			created = tx.Bucket(bucketRunCreatedResources)
+		)
+
+		// keys collected first, bolt forbid changing bucket while iterating it
+		var expired [][]byte
+		err := runs.ForEach(func(k, raw []byte) error {
+			var run services.Run
+			if err := json.Unmarshal(raw, &run); err != nil {
+				return err
+			}
+
+			if e.expired(run, now) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := runs.Delete(k); err != nil {
+				return err
+			}
+			report.ExpiredRuns = append(report.ExpiredRuns, string(k))
+		}
+
+		var dangling [][]byte
+		err = created.ForEach(func(k, _ []byte) error {
+			if runs.Get(k) == nil {
+				dangling = append(dangling, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range dangling {
+			if err := created.DeleteBucket(k); err != nil {
+				return err
+			}
+
+			// expired run's created resources are not dangling
+			if !contains(report.ExpiredRuns, string(k)) {
+				report.DanglingRuns = append(report.DanglingRuns, string(k))
+			}
+		}
+
+		return nil
+	})
+
+	return report, err
+}
+
+// createdResources function return run's created resource within given transaction
+func createdResources(tx *bolt.Tx, runID string) map[services.KubeKind][]string {
+	cr := make(map[services.KubeKind][]string)
+
+	b := tx.Bucket(bucketRunCreatedResources).Bucket([]byte(runID))
+	if b == nil {
+		return cr
+	}
+
+	b.ForEach(func(k, _ []byte) error {
+		parts := strings.SplitN(string(k), "/", 2)
+		kind := services.KubeKind(parts[0])
+		cr[kind] = append(cr[kind], parts[1])
+		return nil
+	})
+
+	return cr
+}
+
+// sequenceKey function return big endian key of given sequence
+// so bolt's byte sorted keys follow sequence order
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// AcquireLock function lock given target when it's free or expired
+// bolt file opened by single process, so the lock only guard runs of that process
+func (e *BoltStore) AcquireLock(target, runID string, ttl time.Duration) (int64, error) {
+	var token int64
+
+	err := e.update("acquire lock", func(tx *bolt.Tx) error {
+		var (
+			key = []byte(target)
+			now = time.Now()
+		)
+
+		record, err := getLockRecord(tx, key)
+		if err != nil {
+			return err
+		}
+		if record != nil && now.Before(record.ExpiresAt) {
+			return &services.TargetLockedError{Target: target, HolderRunID: record.RunID}
+		}
+
+		tokens := tx.Bucket(bucketLockTokens)
+		if raw := tokens.Get(key); raw != nil {
+			token = int64(binary.BigEndian.Uint64(raw))
+		}
+		token++
+
+		if err := tokens.Put(key, sequenceKey(uint64(token))); err != nil {
+			return err
+		}
+
+		return putLockRecord(tx, key, lockRecord{RunID: runID, Token: token, ExpiresAt: now.Add(ttl)})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return token, nil
+}
+
+// RenewLock function extend lock ttl when it's still held by given run
+func (e *BoltStore) RenewLock(lock services.TargetLock, runID string, ttl time.Duration) error {
+	return e.update("renew lock", func(tx *bolt.Tx) error {
+		var (
+			key = []byte(lock.Target)
+			now = time.Now()
+		)
+
+		record, err := getLockRecord(tx, key)
+		if err != nil {
+			return err
+		}
+		if record == nil || !record.held(lock, runID, now) {
+			return services.ErrLockLost
+		}
+
+		record.ExpiresAt = now.Add(ttl)
+		return putLockRecord(tx, key, *record)
+	})
+}
+
+// ReleaseLock function delete lock when it's still held by given run
+func (e *BoltStore) ReleaseLock(lock services.TargetLock, runID string) error {
+	return e.update("release lock", func(tx *bolt.Tx) error {
+		key := []byte(lock.Target)
+
+		record, err := getLockRecord(tx, key)
+		if err != nil {
+			return err
+		}
+		if record == nil || !record.held(lock, runID, time.Now()) {
+			return services.ErrLockLost
+		}
+
+		return tx.Bucket(bucketLocks).Delete(key)
+	})
+}
+
+// getLockRecord function return lock record of given target, nil when it's not locked
+func getLockRecord(tx *bolt.Tx, key []byte) (*lockRecord, error) {
+	raw := tx.Bucket(bucketLocks).Get(key)
+	if raw == nil {
+		return nil, nil
+	}
+
+	var record lockRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// putLockRecord function store lock record of given target
+func putLockRecord(tx *bolt.Tx, key []byte, record lockRecord) error {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketLocks).Put(key, raw)
+}
+
+// Atomic function run given function within single bolt read write transaction
+// the function must not call the store, bolt allow only one writer at a time
+func (e *BoltStore) Atomic(fn func(uow UnitOfWork) error) error {
+	return e.atomic("atomic write", fn)
+}
+
+// atomic function run given function as unit of work of given store operation
+// error of given function returned as is, it's not failure of bolt
+func (e *BoltStore) atomic(op string, fn func(uow UnitOfWork) error) error {
+	var fnErr error
+
+	err := e.update(op, func(tx *bolt.Tx) error {
+		uow := &boltUnitOfWork{tx: tx}
+		if fnErr = fn(uow); fnErr != nil {
+			return fnErr
+		}
+		return uow.err
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+
+	return err
+}
+
+// do function run given write unless earlier write already failed
+func (u *boltUnitOfWork) do(write func() error) {
+	if u.err == nil {
+		u.err = write()
+	}
+}
+
+// Update function write suite
+func (u *boltUnitOfWork) Update(m services.Model) {
+	// resources stored on their own bucket
+	m.Resources = nil
+
+	u.do(func() error {
+		raw, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+
+		return u.tx.Bucket(bucketSuites).Put([]byte(m.ID), raw)
+	})
+}
+
+// CreateResource function write file resource returning it's id
+func (u *boltUnitOfWork) CreateResource(suiteID string, resource services.FileResource) string {
+	resource.ID = uuid.New().String()
+	resource.SuiteID = suiteID
+
+	u.do(func() error {
+		raw, err := json.Marshal(resource)
+		if err != nil {
+			return err
+		}
+
+		b, err := u.tx.Bucket(bucketSuiteResources).CreateBucketIfNotExists([]byte(suiteID))
+		if err != nil {
+			return err
+		}
+
+		// keyed by sequence so resources listed by creation order
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		return b.Put(sequenceKey(seq), raw)
+	})
+
+	return resource.ID
+}
+
+// DeleteSuiteResources function remove all file resources of suite
+func (u *boltUnitOfWork) DeleteSuiteResources(suiteID string) {
+	u.do(func() error {
+		err := u.tx.Bucket(bucketSuiteResources).DeleteBucket([]byte(suiteID))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// SaveRun function write run
+func (u *boltUnitOfWork) SaveRun(run services.Run) {
+	u.do(func() error {
+		raw, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+
+		return u.tx.Bucket(bucketRuns).Put([]byte(run.ID), raw)
+	})
+}
+
+// AppendCreatedResource function write created k8s resource name of run
+func (u *boltUnitOfWork) AppendCreatedResource(runID string, kind services.KubeKind, resourceName string) {
+	u.do(func() error {
+		b, err := u.tx.Bucket(bucketRunCreatedResources).CreateBucketIfNotExists([]byte(runID))
+		if err != nil {
+			return err
+		}
+
+		// kind never contain slash, so the key split back on first slash
+		return b.Put([]byte(string(kind)+"/"+resourceName), []byte(kind))
+	})
+}
@@ -0,0 +1,483 @@
+package resouces
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/google/uuid"
+)
+
+type (
+	// MemoryStore struct is suite store kept on process memory
+	// data lost when process exit, meant for tests and local try out
+	// records kept encoded, so stored data never shared with caller
+	MemoryStore struct {
+		options
+		id string
+		mu sync.RWMutex
+
+		suites    map[string][]byte
+		resources map[string][][]byte // suite id to it's file resources by creation order
+		runs      map[string][]byte
+
+		// run id to created resource names by kind
+		createdResources map[string]map[services.KubeKind][]string
+
+		locks      map[string]lockRecord // lock target to it's lock
+		lockTokens map[string]int64      // lock target to it's last fencing token
+	}
+
+	// memoryUnitOfWork struct queue writes applied under store lock
+	memoryUnitOfWork struct {
+		writes []func(e *MemoryStore)
+		err    error // first error while queueing writes
+	}
+)
+
+// NewMemory function return empty in memory suite store
+// finished runs older than run retention removed on reconcile
+func NewMemory(opts ...Option) *MemoryStore {
+	return &MemoryStore{
+		options:          newOptions(opts),
+		id:               uuid.New().String(),
+		suites:           make(map[string][]byte),
+		resources:        make(map[string][][]byte),
+		runs:             make(map[string][]byte),
+		createdResources: make(map[string]map[services.KubeKind][]string),
+		locks:            make(map[string]lockRecord),
+		lockTokens:       make(map[string]int64),
+	}
+}
+
+// Close function do nothing, memory store has nothing to release
+func (e *MemoryStore) Close() error {
+	return nil
+}
+
+// StoreID function return id of the store, new on every process
+func (e *MemoryStore) StoreID() (string, error) {
+	return e.id, nil
+}
+
+// Create function store new suite with given name returning id of model
+func (e *MemoryStore) Create(name string) (string, error) {
+	m := services.Model{
+		ID:   uuid.New().String(),
+		Name: name,
+	}
+
+	return m.ID, e.Update(m)
+}
+
+// Get function return all suites on store
+func (e *MemoryStore) Get() ([]services.Model, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	suites := []services.Model{}
+	for _, raw := range e.suites {
+		var m services.Model
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return suites, err
+		}
+		suites = append(suites, m)
+	}
+
+	sort.Slice(suites, func(i, j int) bool { return suites[i].ID < suites[j].ID })
+	return suites, nil
+}
+
+// Find function return suite model from given id
+func (e *MemoryStore) Find(id string) (services.Model, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var m services.Model
+
+	raw, ok := e.suites[id]
+	if !ok {
+		return m, ErrSuiteNotFound
+	}
+
+	return m, json.Unmarshal(raw, &m)
+}
+
+// Update function store given suite model under it's id
+// suite will be created when it doesn't exist yet
+func (e *MemoryStore) Update(m services.Model) error {
+	return e.Atomic(func(uow UnitOfWork) error {
+		uow.Update(m)
+		return nil
+	})
+}
+
+// Delete function remove suite and it's resources from store
+func (e *MemoryStore) Delete(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, hasSuite := e.suites[id]
+	_, hasResources := e.resources[id]
+	if !hasSuite && !hasResources {
+		return ErrSuiteNotFound
+	}
+
+	delete(e.suites, id)
+	delete(e.resources, id)
+	return nil
+}
+
+// CreateResource function append file resource to suite
+// returning id of created resource
+func (e *MemoryStore) CreateResource(suiteID string, resource services.FileResource) (string, error) {
+	uow := &memoryUnitOfWork{}
+
+	id := uow.CreateResource(suiteID, resource)
+	if uow.err != nil {
+		return "", uow.err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.suites[suiteID]; !ok {
+		return "", ErrSuiteNotFound
+	}
+
+	uow.apply(e)
+	return id, nil
+}
+
+// GetSuiteResources function return suite resources by creation order
+func (e *MemoryStore) GetSuiteResources(suiteID string) ([]services.FileResource, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var resources []services.FileResource
+
+	if _, ok := e.suites[suiteID]; !ok {
+		return resources, ErrSuiteNotFound
+	}
+	for _, raw := range e.resources[suiteID] {
+		var r services.FileResource
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return resources, err
+		}
+		resources = append(resources, r)
+	}
+
+	return resources, nil
+}
+
+// DeleteResource function remove file resource with given id from suite
+func (e *MemoryStore) DeleteResource(suiteID, resourceID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.suites[suiteID]; !ok {
+		return ErrSuiteNotFound
+	}
+
+	rawResources := e.resources[suiteID]
+	for i, raw := range rawResources {
+		var r services.FileResource
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return err
+		}
+
+		if r.ID == resourceID {
+			e.resources[suiteID] = append(rawResources[:i:i], rawResources[i+1:]...)
+			return nil
+		}
+	}
+
+	return ErrResourceNotFound
+}
+
+// DeleteSuiteResources function remove all file resources of suite
+func (e *MemoryStore) DeleteSuiteResources(suiteID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.suites[suiteID]; !ok {
+		return ErrSuiteNotFound
+	}
+
+	delete(e.resources, suiteID)
+	return nil
+}
+
+// SaveRun function store given run under it's id
+func (e *MemoryStore) SaveRun(run services.Run) error {
+	return e.Atomic(func(uow UnitOfWork) error {
+		uow.SaveRun(run)
+		return nil
+	})
+}
+
+// FindRun function return run from given id
+// including all of it's created resources
+func (e *MemoryStore) FindRun(id string) (services.Run, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var run services.Run
+
+	raw, ok := e.runs[id]
+	if !ok {
+		return run, ErrRunNotFound
+	}
+
+	if err := json.Unmarshal(raw, &run); err != nil {
+		return run, err
+	}
+
+	// created resource appended one by one while run in progress
+	// so it's always more complete than the stored run
+	if cr := e.copyCreatedResources(id); len(cr) > 0 {
+		run.CreatedResources = cr
+	}
+
+	return run, nil
+}
+
+// GetRuns function return all runs on store
+func (e *MemoryStore) GetRuns() ([]services.Run, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	runs := []services.Run{}
+	for _, raw := range e.runs {
+		var run services.Run
+		if err := json.Unmarshal(raw, &run); err != nil {
+			return runs, err
+		}
+		runs = append(runs, run)
+	}
+
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.Before(runs[j].StartedAt) })
+	return runs, nil
+}
+
+// AppendCreatedResource function record created k8s resource name of run
+func (e *MemoryStore) AppendCreatedResource(runID string, kind services.KubeKind, resourceName string) error {
+	return e.Atomic(func(uow UnitOfWork) error {
+		uow.AppendCreatedResource(runID, kind, resourceName)
+		return nil
+	})
+}
+
+// GetRunCreatedResources function return run's created resource
+func (e *MemoryStore) GetRunCreatedResources(runID string) (map[services.KubeKind][]string, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.copyCreatedResources(runID), nil
+}
+
+// copyCreatedResources function return copy of run's created resource
+// caller must hold the lock
+func (e *MemoryStore) copyCreatedResources(runID string) map[services.KubeKind][]string {
+	cr := make(map[services.KubeKind][]string)
+	for kind, names := range e.createdResources[runID] {
+		cr[kind] = append([]string(nil), names...)
+	}
+	return cr
+}
+
+// Reconcile function remove finished runs older than run retention
+// and created resources of missing run
+func (e *MemoryStore) Reconcile() (ReconcileReport, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var (
+		report ReconcileReport
+		now    = time.Now()
+	)
+
+	for id, raw := range e.runs {
+		var run services.Run
+		if err := json.Unmarshal(raw, &run); err != nil {
+			return report, err
+		}
+
+		if e.expired(run, now) {
+			delete(e.runs, id)
+			delete(e.createdResources, id)
+			report.ExpiredRuns = append(report.ExpiredRuns, id)
+		}
+	}
+
+	for id := range e.createdResources {
+		if _, ok := e.runs[id]; !ok {
+			delete(e.createdResources, id)
+			report.DanglingRuns = append(report.DanglingRuns, id)
+		}
+	}
+
+	return report, nil
+}
+
+// AcquireLock function lock given target when it's free or expired
+func (e *MemoryStore) AcquireLock(target, runID string, ttl time.Duration) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+
+	if record, ok := e.locks[target]; ok && now.Before(record.ExpiresAt) {
+		return 0, &services.TargetLockedError{Target: target, HolderRunID: record.RunID}
+	}
+
+	e.lockTokens[target]++
+	token := e.lockTokens[target]
+
+	e.locks[target] = lockRecord{
+		RunID:     runID,
+		Token:     token,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	return token, nil
+}
+
+// RenewLock function extend lock ttl when it's still held by given run
+func (e *MemoryStore) RenewLock(lock services.TargetLock, runID string, ttl time.Duration) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+
+	record, ok := e.locks[lock.Target]
+	if !ok || !record.held(lock, runID, now) {
+		return services.ErrLockLost
+	}
+
+	record.ExpiresAt = now.Add(ttl)
+	e.locks[lock.Target] = record
+	return nil
+}
+
+// ReleaseLock function delete lock when it's still held by given run
+func (e *MemoryStore) ReleaseLock(lock services.TargetLock, runID string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	record, ok := e.locks[lock.Target]
+	if !ok || !record.held(lock, runID, time.Now()) {
+		return services.ErrLockLost
+	}
+
+	delete(e.locks, lock.Target)
+	return nil
+}
+
+// Atomic function collect writes of given function
+// then apply them all at once under store lock
+func (e *MemoryStore) Atomic(fn func(uow UnitOfWork) error) error {
+	uow := &memoryUnitOfWork{}
+	if err := fn(uow); err != nil {
+		return err
+	}
+	if uow.err != nil {
+		return uow.err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	uow.apply(e)
+	return nil
+}
+
+// apply function apply queued writes to given store
+// caller must hold the lock
+func (u *memoryUnitOfWork) apply(e *MemoryStore) {
+	for _, write := range u.writes {
+		write(e)
+	}
+}
+
+// fail function record first error while queueing writes
+func (u *memoryUnitOfWork) fail(err error) {
+	if u.err == nil {
+		u.err = err
+	}
+}
+
+// Update function queue suite write
+func (u *memoryUnitOfWork) Update(m services.Model) {
+	// resources stored on their own
+	m.Resources = nil
+
+	raw, err := json.Marshal(m)
+	if err != nil {
+		u.fail(err)
+		return
+	}
+
+	u.writes = append(u.writes, func(e *MemoryStore) {
+		e.suites[m.ID] = raw
+	})
+}
+
+// CreateResource function queue file resource write returning it's id
+func (u *memoryUnitOfWork) CreateResource(suiteID string, resource services.FileResource) string {
+	resource.ID = uuid.New().String()
+	resource.SuiteID = suiteID
+
+	raw, err := json.Marshal(resource)
+	if err != nil {
+		u.fail(err)
+		return resource.ID
+	}
+
+	u.writes = append(u.writes, func(e *MemoryStore) {
+		e.resources[suiteID] = append(e.resources[suiteID], raw)
+	})
+	return resource.ID
+}
+
+// DeleteSuiteResources function queue removal of all file resources of suite
+func (u *memoryUnitOfWork) DeleteSuiteResources(suiteID string) {
+	u.writes = append(u.writes, func(e *MemoryStore) {
+		delete(e.resources, suiteID)
+	})
+}
+
+// SaveRun function queue run write
+func (u *memoryUnitOfWork) SaveRun(run services.Run) {
+	raw, err := json.Marshal(run)
+	if err != nil {
+		u.fail(err)
+		return
+	}
+
+	u.writes = append(u.writes, func(e *MemoryStore) {
+		e.runs[run.ID] = raw
+	})
+}
+
+// AppendCreatedResource function queue created k8s resource name of run
+func (u *memoryUnitOfWork) AppendCreatedResource(runID string, kind services.KubeKind, resourceName string) {
+	u.writes = append(u.writes, func(e *MemoryStore) {
+		cr, ok := e.createdResources[runID]
+		if !ok {
+			cr = make(map[services.KubeKind][]string)
+			e.createdResources[runID] = cr
+		}
+
+		// resource names are set, same as other backends
+		for _, name := range cr[kind] {
+			if name == resourceName {
+				return
+			}
+		}
+
+		cr[kind] = append(cr[kind], resourceName)
+	})
+}
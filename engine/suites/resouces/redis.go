@@ -0,0 +1,688 @@
+package resouces
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/pkg/cache"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+)
+
+type (
+	// RedisStore struct is suite store backed by redis
+	RedisStore struct {
+		options
+		cache cache.Engine
+	}
+
+	// redisUnitOfWork struct queue writes on redis transaction pipeline
+	redisUnitOfWork struct {
+		store        *RedisStore
+		pipe         redis.Pipeliner
+		err          error    // first error while queueing writes
+		finishedRuns []string // ids of finished runs saved, their created resources expire
+	}
+)
+
+const (
+	keySuites                  = "resilia_suites"
+	keySuite                   = "resilia_suite:%s"
+	keySuiteResources          = "resilia_suite_resources:%s"
+	keyRuns                    = "resilia_runs"
+	keyRun                     = "resilia_run:%s"
+	keyRunCreatedResourcesHash = "resilia_run_created_res_hs:%s"
+	keyRunCreatedResources     = "resilia_run_created_res:%s:%s" // example: key: resilia_run_created_res:1:deployment value : [redis-deployment, postgre-deployment]
+	keyLock                    = "resilia_lock:%s"               // value : <run id>:<fencing token>
+	keyLockToken               = "resilia_lock_token:%s"         // fencing token counter of lock target
+	keyStoreID                 = "resilia_store_id"
+	noExpire                   = time.Duration(0)
+)
+
+var (
+	// acquire lock script set the lock or return it's holder at once, empty when set
+	acquireLockScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return ""
+end
+return redis.call("GET", KEYS[1])`)
+	// lock scripts only touch the lock when it's still held by the caller
+	renewLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+	// expire created resources hash of run along with every names set it list
+	expireCreatedResourcesScript = redis.NewScript(`
+for _, key in ipairs(redis.call("HVALS", KEYS[1])) do
+	redis.call("PEXPIRE", key, ARGV[1])
+end
+return redis.call("PEXPIRE", KEYS[1], ARGV[1])`)
+)
+
+// NewRedis function return suite store backed by given redis
+// suites never expire, finished runs expire after run retention
+func NewRedis(cache cache.Engine, opts ...Option) *RedisStore {
+	return &RedisStore{
+		options: newOptions(opts),
+		cache:   cache,
+	}
+}
+
+// Close function close redis connection
+func (e *RedisStore) Close() error {
+	return e.cache.Close()
+}
+
+// StoreID function return id of the store, generated by the first process
+// asking for it, so every process using the same redis share it
+func (e *RedisStore) StoreID() (string, error) {
+	if err := e.cache.SetNX(keyStoreID, uuid.New().String(), noExpire).Err(); err != nil {
+		return "", unavailable("store id", err)
+	}
+
+	id, err := e.cache.Get(keyStoreID).Result()
+	if err != nil {
+		return "", unavailable("store id", err)
+	}
+
+	return id, nil
+}
+
+// Create function store given suite to database returning id of model
+func (e *RedisStore) Create(name string) (string, error) {
+	var (
+		id = uuid.New().String()
+	)
+
+	m := services.Model{
+		ID:   id,
+		Name: name,
+	}
+
+	err := e.atomic("create suite", func(uow UnitOfWork) error {
+		uow.Update(m)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// Find function return suite model from given id
+func (e *RedisStore) Find(id string) (services.Model, error) {
+	var m services.Model
+	key := fmt.Sprintf(keySuite, id)
+	str, err := e.cache.Get(key).Result()
+	if err == redis.Nil {
+		return m, ErrSuiteNotFound
+	}
+	if err != nil {
+		return m, unavailable("find suite", err)
+	}
+
+	err = json.Unmarshal([]byte(str), &m)
+	if err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// Update function store given suite model under it's id
+// suite will be created when it doesn't exist yet
+func (e *RedisStore) Update(m services.Model) error {
+	return e.atomic("update suite", func(uow UnitOfWork) error {
+		uow.Update(m)
+		return nil
+	})
+}
+
+// Delete function remove suite and it's resources from database
+func (e *RedisStore) Delete(id string) error {
+	var (
+		key     = fmt.Sprintf(keySuite, id)
+		deleted *redis.IntCmd
+		removed *redis.IntCmd
+	)
+
+	_, err := e.cache.TxPipelined(func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(key, fmt.Sprintf(keySuiteResources, id))
+		removed = pipe.SRem(keySuites, key)
+		return nil
+	})
+	if err != nil {
+		return unavailable("delete suite", err)
+	}
+
+	if deleted.Val() == 0 && removed.Val() == 0 {
+		return ErrSuiteNotFound
+	}
+
+	return nil
+}
+
+// suiteExist function return ErrSuiteNotFound when suite with given id doesn't exist
+func (e *RedisStore) suiteExist(op, id string) error {
+	exist, err := e.cache.Exists(fmt.Sprintf(keySuite, id)).Result()
+	if err != nil {
+		return unavailable(op, err)
+	}
+	if exist == 0 {
+		return ErrSuiteNotFound
+	}
+	return nil
+}
+
+// CreateResource function append file resource to suite
+// returning id of created resource
+func (e *RedisStore) CreateResource(suiteID string, resource services.FileResource) (string, error) {
+	if err := e.suiteExist("create resource", suiteID); err != nil {
+		return "", err
+	}
+
+	var id string
+	err := e.atomic("create resource", func(uow UnitOfWork) error {
+		id = uow.CreateResource(suiteID, resource)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// DeleteResource function remove file resource with given id from suite
+func (e *RedisStore) DeleteResource(suiteID, resourceID string) error {
+	key := fmt.Sprintf(keySuiteResources, suiteID)
+
+	if err := e.suiteExist("delete resource", suiteID); err != nil {
+		return err
+	}
+
+	rawResources, err := e.cache.SMembers(key).Result()
+	if err != nil {
+		return unavailable("delete resource", err)
+	}
+
+	for _, rawResource := range rawResources {
+		var r services.FileResource
+		if err := json.Unmarshal([]byte(rawResource), &r); err != nil {
+			return err
+		}
+		if r.ID == resourceID {
+			return unavailable("delete resource", e.cache.SRem(key, rawResource).Err())
+		}
+	}
+
+	return ErrResourceNotFound
+}
+
+// DeleteSuiteResources function remove all file resources of suite
+func (e *RedisStore) DeleteSuiteResources(suiteID string) error {
+	if err := e.suiteExist("delete suite resources", suiteID); err != nil {
+		return err
+	}
+
+	return unavailable("delete suite resources", e.cache.Del(fmt.Sprintf(keySuiteResources, suiteID)).Err())
+}
+
+// GetSuiteResources function return suite resources
+func (e *RedisStore) GetSuiteResources(suiteID string) ([]services.FileResource, error) {
+	var (
+		key       = fmt.Sprintf(keySuiteResources, suiteID)
+		resources []services.FileResource
+	)
+
+	if err := e.suiteExist("get suite resources", suiteID); err != nil {
+		return resources, err
+	}
+
+	rawResources, err := e.cache.SMembers(key).Result()
+	if err != nil {
+		return resources, unavailable("get suite resources", err)
+	}
+
+	for _, rawResource := range rawResources {
+		var r services.FileResource
+		err = json.Unmarshal([]byte(rawResource), &r)
+		if err != nil {
+			return resources, err
+		}
+		resources = append(resources, r)
+	}
+
+	return resources, nil
+}
+
+// SaveRun function store given run under it's id
+func (e *RedisStore) SaveRun(run services.Run) error {
+	return e.atomic("save run", func(uow UnitOfWork) error {
+		uow.SaveRun(run)
+		return nil
+	})
+}
+
+// FindRun function return run from given id
+// including all of it's created resources
+func (e *RedisStore) FindRun(id string) (services.Run, error) {
+	var run services.Run
+
+	str, err := e.cache.Get(fmt.Sprintf(keyRun, id)).Result()
+	if err == redis.Nil {
+		return run, ErrRunNotFound
+	}
+	if err != nil {
+		return run, unavailable("find run", err)
+	}
+
+	err = json.Unmarshal([]byte(str), &run)
+	if err != nil {
+		return run, err
+	}
+
+	// created resource appended one by one while run in progress
+	// so it's always more complete than the stored run
+	cr, err := e.GetRunCreatedResources(id)
+	if err != nil {
+		return run, err
+	}
+	if len(cr) > 0 {
+		run.CreatedResources = cr
+	}
+
+	return run, nil
+}
+
+// GetRuns function return all runs on database
+func (e *RedisStore) GetRuns() ([]services.Run, error) {
+	var (
+		runs = []services.Run{}
+	)
+
+	keys, err := e.cache.SMembers(keyRuns).Result()
+	if err != nil {
+		return runs, unavailable("get runs", err)
+	}
+
+	for _, key := range keys {
+		str, err := e.cache.Get(key).Result()
+		if err == redis.Nil {
+			// run already expired but still listed, removed on reconcile
+			continue
+		}
+		if err != nil {
+			return runs, unavailable("get runs", err)
+		}
+
+		var run services.Run
+		err = json.Unmarshal([]byte(str), &run)
+		if err != nil {
+			return runs, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// AppendCreatedResource function record created k8s resource name of run
+// both kind hash and resource names set written at once, garbage collector rely on it
+func (e *RedisStore) AppendCreatedResource(runID string, kind services.KubeKind, resourceName string) error {
+	return e.atomic("append created resource", func(uow UnitOfWork) error {
+		uow.AppendCreatedResource(runID, kind, resourceName)
+		return nil
+	})
+}
+
+// GetRunCreatedResources function return run's created resource
+func (e *RedisStore) GetRunCreatedResources(runID string) (map[services.KubeKind][]string, error) {
+
+	var (
+		cr      = make(map[services.KubeKind][]string)
+		err     error
+		keyHash = fmt.Sprintf(keyRunCreatedResourcesHash, runID)
+	)
+
+	keyLists, err := e.cache.HGetAll(keyHash).Result()
+	if err != nil {
+		return cr, unavailable("get run created resources", err)
+	}
+
+	for kind, keyList := range keyLists {
+		resourceName, err := e.cache.SMembers(keyList).Result()
+		if err != nil {
+			return cr, unavailable("get run created resources", err)
+		}
+		kk := services.KubeKind(kind)
+		cr[kk] = append(cr[kk], resourceName...)
+	}
+
+	return cr, err
+
+}
+
+// Get function return all suites on database
+func (e *RedisStore) Get() ([]services.Model, error) {
+	var (
+		suites = []services.Model{}
+	)
+
+	keys, err := e.cache.SMembers(keySuites).Result()
+	if err != nil {
+		return suites, unavailable("get suites", err)
+	}
+
+	for _, key := range keys {
+		str, err := e.cache.Get(key).Result()
+		if err == redis.Nil {
+			// suite key gone but still listed, removed on reconcile
+			continue
+		}
+		if err != nil {
+			return suites, unavailable("get suites", err)
+		}
+
+		var m services.Model
+		err = json.Unmarshal([]byte(str), &m)
+		if err != nil {
+			return suites, err
+		}
+		suites = append(suites, m)
+	}
+
+	return suites, nil
+}
+
+// Reconcile function remove suites and runs index entries whose key already gone
+// and created resources of missing run, suites stored before suites were made
+// durable persisted, so they stop expiring
+// expired runs reported as dangling runs, since redis expire them by itself
+func (e *RedisStore) Reconcile() (ReconcileReport, error) {
+	var (
+		report ReconcileReport
+		err    error
+	)
+
+	report.DanglingSuites, err = e.reconcileIndex(keySuites, keySuite, true)
+	if err != nil {
+		return report, err
+	}
+
+	report.DanglingRuns, err = e.reconcileIndex(keyRuns, keyRun, false)
+	if err != nil {
+		return report, err
+	}
+
+	// created resources appended without expire, run might be gone
+	// without ever being saved as finished, eg: stored by older version
+	iter := e.cache.Scan(0, fmt.Sprintf(keyRunCreatedResourcesHash, "*"), 0).Iterator()
+	for iter.Next() {
+		var (
+			keyHash = iter.Val()
+			runID   = strings.TrimPrefix(keyHash, fmt.Sprintf(keyRunCreatedResourcesHash, ""))
+		)
+
+		exist, err := e.cache.Exists(fmt.Sprintf(keyRun, runID)).Result()
+		if err != nil {
+			return report, unavailable("reconcile", err)
+		}
+		if exist > 0 {
+			continue
+		}
+
+		keyLists, err := e.cache.HVals(keyHash).Result()
+		if err != nil {
+			return report, unavailable("reconcile", err)
+		}
+
+		if err := e.cache.Del(append(keyLists, keyHash)...).Err(); err != nil {
+			return report, unavailable("reconcile", err)
+		}
+
+		if !contains(report.DanglingRuns, runID) {
+			report.DanglingRuns = append(report.DanglingRuns, runID)
+		}
+	}
+
+	return report, unavailable("reconcile", iter.Err())
+}
+
+// reconcileIndex function remove index set members whose key doesn't exist
+// returning ids of removed members, persist existing keys when asked
+func (e *RedisStore) reconcileIndex(keyIndex, keyFormat string, persist bool) ([]string, error) {
+	var (
+		dangling []string
+		prefix   = fmt.Sprintf(keyFormat, "")
+	)
+
+	keys, err := e.cache.SMembers(keyIndex).Result()
+	if err != nil {
+		return dangling, unavailable("reconcile", err)
+	}
+
+	for _, key := range keys {
+		exist, err := e.cache.Exists(key).Result()
+		if err != nil {
+			return dangling, unavailable("reconcile", err)
+		}
+
+		if exist > 0 {
+			if persist {
+				if err := e.cache.Persist(key).Err(); err != nil {
+					return dangling, unavailable("reconcile", err)
+				}
+			}
+			continue
+		}
+
+		if err := e.cache.SRem(keyIndex, key).Err(); err != nil {
+			return dangling, unavailable("reconcile", err)
+		}
+		dangling = append(dangling, strings.TrimPrefix(key, prefix))
+	}
+
+	return dangling, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Atomic function queue writes of given function on redis transaction
+// applied with single MULTI/EXEC once the function return
+func (e *RedisStore) Atomic(fn func(uow UnitOfWork) error) error {
+	return e.atomic("atomic write", fn)
+}
+
+// atomic function run given function as unit of work of given store operation
+// failure of redis returned as store error, error of the function returned as it is
+func (e *RedisStore) atomic(op string, fn func(uow UnitOfWork) error) error {
+	var fnErr error
+
+	_, err := e.cache.TxPipelined(func(pipe redis.Pipeliner) error {
+		uow := &redisUnitOfWork{store: e, pipe: pipe}
+		if fnErr = fn(uow); fnErr == nil {
+			fnErr = uow.err
+		}
+		if fnErr == nil {
+			uow.expireCreatedResources()
+		}
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		return unavailable(op, err)
+	}
+
+	return err
+}
+
+// fail function record first error while queueing writes
+// the error abort the transaction
+func (u *redisUnitOfWork) fail(err error) {
+	if u.err == nil {
+		u.err = err
+	}
+}
+
+// Update function queue suite write, suite never expire
+func (u *redisUnitOfWork) Update(m services.Model) {
+	key := fmt.Sprintf(keySuite, m.ID)
+
+	// resources stored on their own key
+	m.Resources = nil
+
+	byteModel, err := json.Marshal(m)
+	if err != nil {
+		u.fail(err)
+		return
+	}
+
+	u.pipe.Set(key, string(byteModel), noExpire)
+	u.pipe.SAdd(keySuites, key)
+}
+
+// CreateResource function queue file resource write returning it's id
+func (u *redisUnitOfWork) CreateResource(suiteID string, resource services.FileResource) string {
+	resource.ID = uuid.New().String()
+	resource.SuiteID = suiteID
+
+	res, err := json.Marshal(resource)
+	if err != nil {
+		u.fail(err)
+		return resource.ID
+	}
+
+	u.pipe.SAdd(fmt.Sprintf(keySuiteResources, suiteID), res)
+	return resource.ID
+}
+
+// DeleteSuiteResources function queue removal of all file resources of suite
+func (u *redisUnitOfWork) DeleteSuiteResources(suiteID string) {
+	u.pipe.Del(fmt.Sprintf(keySuiteResources, suiteID))
+}
+
+// SaveRun function queue run write, finished run expire
+// along with it's created resources after run retention
+func (u *redisUnitOfWork) SaveRun(run services.Run) {
+	key := fmt.Sprintf(keyRun, run.ID)
+
+	byteRun, err := json.Marshal(run)
+	if err != nil {
+		u.fail(err)
+		return
+	}
+
+	// unfinished run kept until it's finished, garbage collector
+	// rely on it to tell whether run's objects still in use
+	expire := noExpire
+	if run.IsFinished() {
+		expire = u.store.runRetention
+	}
+
+	u.pipe.Set(key, string(byteRun), expire)
+	u.pipe.SAdd(keyRuns, key)
+
+	if expire != noExpire {
+		u.finishedRuns = append(u.finishedRuns, run.ID)
+	}
+}
+
+// expireCreatedResources function queue expiry of finished runs' created resources
+// queued last and resolved by redis on EXEC, so created resources appended
+// on the same unit of work, before or after the run saved, expire as well
+func (u *redisUnitOfWork) expireCreatedResources() {
+	for _, runID := range u.finishedRuns {
+		keyHash := fmt.Sprintf(keyRunCreatedResourcesHash, runID)
+		expireCreatedResourcesScript.Eval(u.pipe, []string{keyHash}, u.store.runRetention.Milliseconds())
+	}
+}
+
+// AppendCreatedResource function queue created k8s resource name of run
+func (u *redisUnitOfWork) AppendCreatedResource(runID string, kind services.KubeKind, resourceName string) {
+	keyHash := fmt.Sprintf(keyRunCreatedResourcesHash, runID)
+	keyList := fmt.Sprintf(keyRunCreatedResources, runID, kind)
+
+	u.pipe.HSet(keyHash, string(kind), keyList)
+	u.pipe.SAdd(keyList, resourceName)
+}
+
+// AcquireLock function lock given target using SET NX PX, the lock value
+// hold run id and fencing token taken from target's token counter
+func (e *RedisStore) AcquireLock(target, runID string, ttl time.Duration) (int64, error) {
+	token, err := e.cache.Incr(fmt.Sprintf(keyLockToken, target)).Result()
+	if err != nil {
+		return 0, unavailable("acquire lock", err)
+	}
+
+	// set and holder read in single script, lock expiring right after
+	// SET NX failed would be reported as held by nobody otherwise
+	key := fmt.Sprintf(keyLock, target)
+	holder, err := acquireLockScript.Run(e.cache, []string{key}, lockValue(runID, token), ttl.Milliseconds()).String()
+	if err != nil {
+		return 0, unavailable("acquire lock", err)
+	}
+	if holder == "" {
+		return token, nil
+	}
+
+	return 0, &services.TargetLockedError{Target: target, HolderRunID: lockHolder(holder)}
+}
+
+// RenewLock function extend lock ttl when it's still held by given run
+func (e *RedisStore) RenewLock(lock services.TargetLock, runID string, ttl time.Duration) error {
+	key := fmt.Sprintf(keyLock, lock.Target)
+
+	renewed, err := renewLockScript.Run(e.cache, []string{key}, lockValue(runID, lock.Token), ttl.Milliseconds()).Int64()
+	if err != nil {
+		return unavailable("renew lock", err)
+	}
+	if renewed == 0 {
+		return services.ErrLockLost
+	}
+
+	return nil
+}
+
+// ReleaseLock function delete lock when it's still held by given run
+func (e *RedisStore) ReleaseLock(lock services.TargetLock, runID string) error {
+	key := fmt.Sprintf(keyLock, lock.Target)
+
+	released, err := releaseLockScript.Run(e.cache, []string{key}, lockValue(runID, lock.Token)).Int64()
+	if err != nil {
+		return unavailable("release lock", err)
+	}
+	if released == 0 {
+		return services.ErrLockLost
+	}
+
+	return nil
+}
+
+// lockValue function return lock value of given run and fencing token
+func lockValue(runID string, token int64) string {
+	return fmt.Sprintf("%s:%d", runID, token)
+}
+
+// lockHolder function return run id of given lock value
+func lockHolder(value string) string {
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		return value[:i]
+	}
+	return value
+}
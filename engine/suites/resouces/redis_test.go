@@ -0,0 +1,288 @@
+package resouces
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/pkg/cache"
+)
+
+// redisStandIn struct is in process redis server speaking RESP
+// replying every command with given reply function
+type redisStandIn struct {
+	listener net.Listener
+	reply    func(args []string) string
+}
+
+// newRedisStandIn function start redis stand in on random local port
+func newRedisStandIn(t *testing.T, reply func(args []string) string) *redisStandIn {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &redisStandIn{listener: l, reply: reply}
+	go r.accept()
+	return r
+}
+
+func (r *redisStandIn) Addr() string {
+	return r.listener.Addr().String()
+}
+
+func (r *redisStandIn) Close() error {
+	return r.listener.Close()
+}
+
+func (r *redisStandIn) accept() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+		go r.serve(conn)
+	}
+}
+
+// serve function reply commands of single connection, command inside
+// MULTI queued and replied on EXEC, EXEC aborted when any of it failed
+func (r *redisStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var (
+		rd      = bufio.NewReader(conn)
+		multi   bool
+		aborted bool
+		queued  []string
+	)
+
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "MULTI":
+			multi, aborted, queued = true, false, nil
+			reply = "+OK\r\n"
+		case "EXEC":
+			if aborted {
+				reply = "-EXECABORT Transaction discarded because of previous errors.\r\n"
+			} else {
+				reply = fmt.Sprintf("*%d\r\n%s", len(queued), strings.Join(queued, ""))
+			}
+			multi = false
+		default:
+			reply = r.reply(args)
+			if multi {
+				if strings.HasPrefix(reply, "-") {
+					aborted = true
+				} else {
+					queued = append(queued, reply)
+					reply = "+QUEUED\r\n"
+				}
+			}
+		}
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand function read single command sent as RESP array of bulk strings
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	n, err := readLength(rd, '*')
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		size, err := readLength(rd, '$')
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, size+2) // including trailing \r\n
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+func readLength(rd *bufio.Reader, prefix byte) (int, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	if len(line) < 3 || line[0] != prefix {
+		return 0, fmt.Errorf("unexpected line %q", line)
+	}
+	return strconv.Atoi(strings.TrimSpace(line[1:]))
+}
+
+// emptyRedis function reply command as redis holding no key
+func emptyRedis(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		return "$-1\r\n"
+	case "SMEMBERS", "HGETALL", "HVALS":
+		return "*0\r\n"
+	case "EXISTS", "DEL", "SREM", "EVALSHA":
+		return ":0\r\n"
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+}
+
+func newTestRedis(addr string) *RedisStore {
+	return NewRedis(cache.New(addr), WithRunRetention(time.Hour))
+}
+
+func TestRedisStoreNotFound(t *testing.T) {
+	r := newRedisStandIn(t, emptyRedis)
+	defer r.Close()
+
+	s := newTestRedis(r.Addr())
+	defer s.Close()
+
+	var calls []storeCall
+	for _, c := range storeCalls {
+		// the rest write or list, needing stand in that keep data
+		if _, ok := notFoundCalls[c.name]; ok {
+			calls = append(calls, c)
+		}
+	}
+
+	for _, tt := range calls {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(s)
+			if want := notFoundCalls[tt.name]; err != want {
+				t.Errorf("error = %v, want %v", err, want)
+			}
+		})
+	}
+}
+
+func TestRedisStoreUnavailable(t *testing.T) {
+	t.Run("error reply", func(t *testing.T) {
+		r := newRedisStandIn(t, func(args []string) string {
+			return "-ERR OOM command not allowed when used memory > 'maxmemory'.\r\n"
+		})
+		defer r.Close()
+
+		s := newTestRedis(r.Addr())
+		defer s.Close()
+
+		testStoreUnavailable(t, s, storeCalls)
+	})
+
+	t.Run("down", func(t *testing.T) {
+		// nothing listen on address of closed stand in
+		r := newRedisStandIn(t, emptyRedis)
+		r.Close()
+
+		s := newTestRedis(r.Addr())
+		defer s.Close()
+
+		testStoreUnavailable(t, s, storeCalls)
+	})
+}
+
+func TestRedisAtomicExpireCreatedResources(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		commands []string
+	)
+
+	r := newRedisStandIn(t, func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		args[0] = strings.ToUpper(args[0])
+		switch args[0] {
+		case "SET":
+			// run record left out, only it's key matter
+			args = args[:2]
+		case "EVAL":
+			// script body left out, only it's keys and args matter
+			args = append(args[:1], args[2:]...)
+		}
+		commands = append(commands, strings.Join(args, " "))
+		return ":1\r\n"
+	})
+	defer r.Close()
+
+	s := newTestRedis(r.Addr())
+	defer s.Close()
+
+	err := s.Atomic(func(uow UnitOfWork) error {
+		uow.SaveRun(services.Run{ID: "run", Status: services.RunStatusSucceeded})
+		uow.AppendCreatedResource("run", services.KindDeployment, "redis")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Atomic() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// nothing read before the transaction, expiry queued after every write
+	want := []string{
+		"SET resilia_run:run",
+		"SADD resilia_runs resilia_run:run",
+		"HSET resilia_run_created_res_hs:run deployment resilia_run_created_res:run:deployment",
+		"SADD resilia_run_created_res:run:deployment redis",
+		"EVAL 1 resilia_run_created_res_hs:run 3600000",
+	}
+
+	if !reflect.DeepEqual(commands, want) {
+		t.Errorf("commands\n got = %q\nwant = %q", commands, want)
+	}
+}
+
+func TestRedisAcquireLockHolderExpired(t *testing.T) {
+	// lock flapping between other run and nobody, so it's held on every
+	// SET NX and already expired on the GET sent right after it
+	r := newRedisStandIn(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "INCR":
+			return ":2\r\n"
+		case "SET", "GET":
+			return "$-1\r\n"
+		case "EVALSHA":
+			return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+		case "EVAL":
+			// script run at once, lock still held by other run
+			return "$11\r\nother-run:1\r\n"
+		}
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	})
+	defer r.Close()
+
+	s := newTestRedis(r.Addr())
+	defer s.Close()
+
+	_, err := s.AcquireLock(testLock.Target, "run", time.Minute)
+
+	var lockedErr *services.TargetLockedError
+	if !errors.As(err, &lockedErr) || lockedErr.HolderRunID != "other-run" {
+		t.Errorf("AcquireLock() error = %v, want target locked by other-run", err)
+	}
+}
@@ -3,165 +3,178 @@
 package resouces
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/faruqisan/resilia/engine/suites/services"
-	"github.com/faruqisan/resilia/pkg/cache"
-	"github.com/google/uuid"
 )
 
 type (
-	// Engine struct define all resource required params
-	// like db, redis, or another external service
-	Engine struct {
-		cache cache.Engine
+	// SuiteStore interface define storage contract of suites, their file
+	// resources, runs and run's created resources, every backend implement it
+	SuiteStore interface {
+		Create(name string) (string, error)
+		Get() ([]services.Model, error)
+		Find(id string) (services.Model, error)
+		Update(m services.Model) error
+		Delete(id string) error
+
+		CreateResource(suiteID string, resource services.FileResource) (string, error)
+		GetSuiteResources(suiteID string) ([]services.FileResource, error)
+		DeleteResource(suiteID, resourceID string) error
+		DeleteSuiteResources(suiteID string) error
+
+		SaveRun(run services.Run) error
+		FindRun(id string) (services.Run, error)
+		GetRuns() ([]services.Run, error)
+		AppendCreatedResource(runID string, kind services.KubeKind, resourceName string) error
+		GetRunCreatedResources(runID string) (map[services.KubeKind][]string, error)
+
+		// AcquireLock lock given target for given run returning it's fencing token
+		// *services.TargetLockedError returned when target held by other run
+		AcquireLock(target, runID string, ttl time.Duration) (int64, error)
+		// RenewLock extend lock held by given run, services.ErrLockLost returned when it's not held anymore
+		RenewLock(lock services.TargetLock, runID string, ttl time.Duration) error
+		// ReleaseLock release lock held by given run, services.ErrLockLost returned when it's not held anymore
+		ReleaseLock(lock services.TargetLock, runID string) error
+
+		// Atomic run given function and apply writes it queued on unit of work
+		// all or nothing, nothing applied when the function return error
+		// the function must only use the unit of work, not the store itself
+		Atomic(fn func(uow UnitOfWork) error) error
+
+		// StoreID return id of the store, the same for every process using it
+		StoreID() (string, error)
+
+		Reconcile() (ReconcileReport, error)
+		Close() error
 	}
-)
-
-const (
-	keySuites                    = "resilia_suites"
-	keySuite                     = "resilia_suite:%s"
-	keySuiteResources            = "resilia_suite_resources:%s"
-	keySuiteResourcesCreatedHash = "resilia_suite_created_res_hs:%s"
-	keySuiteCreatedResources     = "resilia_suite_created_res:%s:%s" // example: key: resilia_suite_created_res:1:deployment value : [redis-deployment, postgre-deployment]
-	cacheExpire                  = time.Hour * 24                    // 24h expire
-)
 
-// New function return setuped resources engine
-func New(cache cache.Engine) *Engine {
-	return &Engine{
-		cache: cache,
+	// UnitOfWork interface define writes grouped by SuiteStore.Atomic
+	// writes only queued, they're applied once the atomic function return
+	// so suite existence not checked, suite meant to be written on the same unit
+	UnitOfWork interface {
+		Update(m services.Model)
+		CreateResource(suiteID string, resource services.FileResource) string
+		DeleteSuiteResources(suiteID string)
+		SaveRun(run services.Run)
+		AppendCreatedResource(runID string, kind services.KubeKind, resourceName string)
 	}
-}
 
-// Create function store given suite to database returning id of model
-func (e *Engine) Create(name string) (string, error) {
-	var (
-		id = uuid.New().String()
-	)
+	// Option type used to customize suite store
+	Option func(*options)
 
-	m := services.Model{
-		ID:   id,
-		Name: name,
+	// options struct hold settings shared by every backend
+	options struct {
+		runRetention time.Duration
 	}
 
-	key := fmt.Sprintf(keySuite, id)
-	err := e.setSuiteToCache(key, m)
-	if err != nil {
-		return "", err
-	}
-
-	err = e.cache.SAdd(keySuites, key).Err()
-
-	return id, err
-}
-
-// Find function return suite model from given id
-func (e *Engine) Find(id string) (services.Model, error) {
-	var m services.Model
-	key := fmt.Sprintf(keySuite, id)
-	str, err := e.cache.Get(key).Result()
-	if err != nil {
-		return m, err
+	// StoreError struct define backend failure of store operation
+	// it match ErrStoreUnavailable, use errors.Is to check it
+	StoreError struct {
+		Op  string // store operation, eg: find suite
+		Err error  // backend error
 	}
 
-	err = json.Unmarshal([]byte(str), &m)
-	if err != nil {
-		return m, err
+	// lockRecord struct define target lock stored by memory and bolt store
+	lockRecord struct {
+		RunID     string    `json:"run_id"`
+		Token     int64     `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
 	}
-	return m, nil
-}
 
-func (e *Engine) setSuiteToCache(key string, m services.Model) error {
-	byteModel, err := json.Marshal(m)
-	if err != nil {
-		return err
+	// ReconcileReport struct hold result of store reconciliation
+	ReconcileReport struct {
+		// DanglingSuites suite ids listed or referenced without the suite itself
+		DanglingSuites []string `json:"dangling_suites"`
+		// DanglingRuns run ids listed or having created resources without the run itself
+		DanglingRuns []string `json:"dangling_runs"`
+		// ExpiredRuns finished run ids removed since it's older than run retention
+		ExpiredRuns []string `json:"expired_runs"`
 	}
+)
 
-	return e.cache.Set(key, string(byteModel), cacheExpire).Err()
+// Error function return error message of store error
+func (e *StoreError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrStoreUnavailable, e.Op, e.Err)
 }
 
-// CreateResource function append file resource to suite
-func (e *Engine) CreateResource(suiteID string, resource services.FileResource) error {
+// Unwrap function return backend error of store error
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
 
-	key := fmt.Sprintf(keySuiteResources, suiteID)
+// Is function report store error as ErrStoreUnavailable
+func (e *StoreError) Is(target error) bool {
+	return target == ErrStoreUnavailable
+}
 
-	res, err := json.Marshal(resource)
-	if err != nil {
+// unavailable function wrap given backend error of store operation
+// nil returned when there's no error
+func unavailable(op string, err error) error {
+	if err == nil {
 		return nil
 	}
-
-	return e.cache.SAdd(key, res).Err()
+	return &StoreError{Op: op, Err: err}
 }
 
-// GetSuiteResources function return suite resources
-func (e *Engine) GetSuiteResources(suiteID string) ([]services.FileResource, error) {
-	var (
-		key       = fmt.Sprintf(keySuiteResources, suiteID)
-		resources []services.FileResource
-	)
-
-	rawResources, err := e.cache.SMembers(key).Result()
-	if err != nil {
-		return resources, nil
-	}
-
-	for _, rawResource := range rawResources {
-		var r services.FileResource
-		err = json.Unmarshal([]byte(rawResource), &r)
-		if err != nil {
-			return resources, err
-		}
-		resources = append(resources, r)
+// WithRunRetention function set how long finished run kept on store
+// suites are never expired, zero retention (default) keep runs forever
+func WithRunRetention(retention time.Duration) Option {
+	return func(o *options) {
+		o.runRetention = retention
 	}
-
-	return resources, nil
 }
 
-// AppendCreatedResource function
-func (e *Engine) AppendCreatedResource(suiteID, kind, resourceName string) error {
-	// hset and sadd
-	keyHash := fmt.Sprintf(keySuiteResourcesCreatedHash, suiteID)
-	keyList := fmt.Sprintf(keySuiteCreatedResources, suiteID, kind)
-
-	err := e.cache.HSet(keyHash, kind, keyList).Err()
-	if err != nil {
-		return err
+// newOptions function return options with given option applied
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
 	}
-
-	return e.cache.SAdd(keyList, resourceName).Err()
+	return o
 }
 
-// GetSuiteCreatedResource function return suite's created resource
-func (e *Engine) GetSuiteCreatedResource(suiteID string) (map[services.KubeKind][]string, error) {
-
-	var (
-		cr      = make(map[services.KubeKind][]string)
-		err     error
-		keyHash = fmt.Sprintf(keySuiteResourcesCreatedHash, suiteID)
-	)
-
-	keyLists, err := e.cache.HGetAll(keyHash).Result()
-	if err != nil {
-		return cr, err
+// expired function return whether given run older than run retention
+// unfinished run never expire, it's objects still need to be tracked
+func (o options) expired(run services.Run, now time.Time) bool {
+	if o.runRetention <= 0 || !run.IsFinished() {
+		return false
 	}
 
-	for kind, keyList := range keyLists {
-		resourceName, err := e.cache.SMembers(keyList).Result()
-		if err != nil {
-			return cr, err
-		}
-		kk := services.KubeKind(kind)
-		cr[kk] = append(cr[kk], resourceName...)
+	finishedAt := run.StartedAt
+	if run.EndedAt != nil {
+		finishedAt = *run.EndedAt
 	}
 
-	return cr, err
-
+	return now.Sub(finishedAt) > o.runRetention
 }
 
-// Get function return all suites on database
-func (e *Engine) Get() ([]services.Model, error) {
-	return []services.Model{}, nil
+var (
+	// ErrSuiteNotFound returned when suite with given id doesn't exist
+	ErrSuiteNotFound = errors.New("suite not found")
+	// ErrRunNotFound returned when run with given id doesn't exist
+	// it's the suite services error, so services able to recognize it
+	ErrRunNotFound = services.ErrRunNotFound
+	// ErrResourceNotFound returned when suite file resource with given id doesn't exist
+	ErrResourceNotFound = errors.New("resource not found")
+	// ErrStoreUnavailable matched by every StoreError, returned when backend
+	// fail to serve the request, eg: redis down, so it's not telling data missing
+	ErrStoreUnavailable = errors.New("store unavailable")
+	// ErrStoreLocked returned when bolt file held by other process, eg : resilia serve
+	// it's wrapped on StoreError, so it's store unavailable as well
+	ErrStoreLocked = errors.New("bolt file locked by other process")
+)
+
+// held function return whether lock record still held by given lock of run
+func (r lockRecord) held(lock services.TargetLock, runID string, now time.Time) bool {
+	return r.RunID == runID && r.Token == lock.Token && now.Before(r.ExpiresAt)
 }
+
+// compile time check every backend implement suite store
+var (
+	_ SuiteStore = (*RedisStore)(nil)
+	_ SuiteStore = (*MemoryStore)(nil)
+	_ SuiteStore = (*BoltStore)(nil)
+)
@@ -0,0 +1,218 @@
+package resouces
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+)
+
+// storeCall struct define single store operation called by store tests
+type storeCall struct {
+	name string
+	call func(s SuiteStore) error
+}
+
+var (
+	testLock = services.TargetLock{Target: "default/deployment/redis", Token: 1}
+
+	// storeCalls cover every store operation, called on empty store
+	storeCalls = []storeCall{
+		{"store id", func(s SuiteStore) error { _, err := s.StoreID(); return err }},
+		{"create", func(s SuiteStore) error { _, err := s.Create("suite"); return err }},
+		{"get", func(s SuiteStore) error { _, err := s.Get(); return err }},
+		{"find", func(s SuiteStore) error { _, err := s.Find("missing"); return err }},
+		{"update", func(s SuiteStore) error { return s.Update(services.Model{ID: "suite"}) }},
+		{"delete", func(s SuiteStore) error { return s.Delete("missing") }},
+		{"create resource", func(s SuiteStore) error {
+			_, err := s.CreateResource("missing", services.FileResource{Kind: services.KindDeployment})
+			return err
+		}},
+		{"get suite resources", func(s SuiteStore) error { _, err := s.GetSuiteResources("missing"); return err }},
+		{"delete resource", func(s SuiteStore) error { return s.DeleteResource("missing", "missing") }},
+		{"delete suite resources", func(s SuiteStore) error { return s.DeleteSuiteResources("missing") }},
+		{"save run", func(s SuiteStore) error {
+			return s.SaveRun(services.Run{ID: "run", Status: services.RunStatusSucceeded})
+		}},
+		{"find run", func(s SuiteStore) error { _, err := s.FindRun("missing"); return err }},
+		{"get runs", func(s SuiteStore) error { _, err := s.GetRuns(); return err }},
+		{"append created resource", func(s SuiteStore) error {
+			return s.AppendCreatedResource("run", services.KindDeployment, "redis")
+		}},
+		{"get run created resources", func(s SuiteStore) error { _, err := s.GetRunCreatedResources("missing"); return err }},
+		{"acquire lock", func(s SuiteStore) error { _, err := s.AcquireLock(testLock.Target, "run", time.Minute); return err }},
+		// lock acquired above held by run, never by other run
+		{"renew lock", func(s SuiteStore) error { return s.RenewLock(testLock, "other-run", time.Minute) }},
+		{"release lock", func(s SuiteStore) error { return s.ReleaseLock(testLock, "other-run") }},
+		{"atomic", func(s SuiteStore) error {
+			return s.Atomic(func(uow UnitOfWork) error {
+				uow.SaveRun(services.Run{ID: "run", Status: services.RunStatusRunning})
+				return nil
+			})
+		}},
+		{"reconcile", func(s SuiteStore) error { _, err := s.Reconcile(); return err }},
+	}
+
+	// notFoundCalls define error of store operation on missing record
+	notFoundCalls = map[string]error{
+		"find":                   ErrSuiteNotFound,
+		"delete":                 ErrSuiteNotFound,
+		"create resource":        ErrSuiteNotFound,
+		"get suite resources":    ErrSuiteNotFound,
+		"delete resource":        ErrSuiteNotFound,
+		"delete suite resources": ErrSuiteNotFound,
+		"find run":               ErrRunNotFound,
+		"renew lock":             services.ErrLockLost,
+		"release lock":           services.ErrLockLost,
+	}
+)
+
+func newTestBolt(t *testing.T) (*BoltStore, func()) {
+	dir, err := ioutil.TempDir("", "resilia-bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewBolt(filepath.Join(dir, "resilia.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// testStoreNotFound function check store tell missing record apart from backend failure
+func testStoreNotFound(t *testing.T, s SuiteStore) {
+	for _, tt := range storeCalls {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(s)
+
+			if errors.Is(err, ErrStoreUnavailable) {
+				t.Fatalf("error = %v, want not store unavailable", err)
+			}
+
+			want, ok := notFoundCalls[tt.name]
+			if !ok {
+				if err != nil {
+					t.Fatalf("error = %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, want) {
+				t.Errorf("error = %v, want %v", err, want)
+			}
+		})
+	}
+}
+
+// testStoreUnavailable function check given store operations report failing backend
+func testStoreUnavailable(t *testing.T, s SuiteStore, calls []storeCall) {
+	for _, tt := range calls {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(s)
+
+			if !errors.Is(err, ErrStoreUnavailable) {
+				t.Fatalf("error = %v, want %v", err, ErrStoreUnavailable)
+			}
+
+			var storeErr *StoreError
+			if !errors.As(err, &storeErr) || storeErr.Op == "" || storeErr.Err == nil {
+				t.Errorf("error = %#v, want *StoreError with operation and backend error", err)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreNotFound(t *testing.T) {
+	testStoreNotFound(t, NewMemory())
+}
+
+func TestBoltStoreNotFound(t *testing.T) {
+	s, cleanup := newTestBolt(t)
+	defer cleanup()
+
+	testStoreNotFound(t, s)
+}
+
+func TestBoltStoreUnavailable(t *testing.T) {
+	s, cleanup := newTestBolt(t)
+	defer cleanup()
+
+	// every transaction fail once bolt file closed
+	s.Close()
+
+	var calls []storeCall
+	for _, c := range storeCalls {
+		// store id kept on memory since bolt file opened
+		if c.name != "store id" {
+			calls = append(calls, c)
+		}
+	}
+
+	testStoreUnavailable(t, s, calls)
+}
+
+func TestBoltStoreLocked(t *testing.T) {
+	s, cleanup := newTestBolt(t)
+	defer cleanup()
+
+	defer func(timeout time.Duration) { boltOpenTimeout = timeout }(boltOpenTimeout)
+	boltOpenTimeout = 50 * time.Millisecond
+
+	// file held by the store above, eg : by resilia serve
+	other, err := NewBolt(s.db.Path())
+	if err == nil {
+		other.Close()
+		t.Fatal("NewBolt() of locked file want error")
+	}
+
+	if !errors.Is(err, ErrStoreLocked) || !errors.Is(err, ErrStoreUnavailable) {
+		t.Errorf("NewBolt() error = %v, want %v and %v", err, ErrStoreLocked, ErrStoreUnavailable)
+	}
+}
+
+func TestStoreAtomic(t *testing.T) {
+	bolt, cleanup := newTestBolt(t)
+	defer cleanup()
+
+	for name, s := range map[string]SuiteStore{"memory": NewMemory(), "bolt": bolt} {
+		t.Run(name, func(t *testing.T) {
+			fnErr := errors.New("validation failed")
+
+			err := s.Atomic(func(uow UnitOfWork) error {
+				uow.Update(services.Model{ID: "suite", Name: "redis"})
+				return fnErr
+			})
+			if err != fnErr {
+				t.Fatalf("Atomic() error = %v, want function error as is", err)
+			}
+
+			if _, err := s.Find("suite"); !errors.Is(err, ErrSuiteNotFound) {
+				t.Fatalf("Find() error = %v, want %v, write of failed unit applied", err, ErrSuiteNotFound)
+			}
+
+			err = s.Atomic(func(uow UnitOfWork) error {
+				uow.Update(services.Model{ID: "suite", Name: "redis"})
+				uow.CreateResource("suite", services.FileResource{Kind: services.KindDeployment})
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Atomic() error = %v", err)
+			}
+
+			resources, err := s.GetSuiteResources("suite")
+			if err != nil || len(resources) != 1 {
+				t.Errorf("GetSuiteResources() = %v, %v, want single resource", resources, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// duration function return parsed suite run duration
+// zero duration returned when suite run until stopped
+func (m *Model) duration() (time.Duration, error) {
+	if m.Duration == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(m.Duration)
+	if err != nil {
+		return 0, fmt.Errorf("%w: duration: %s", ErrInvalidSuite, err)
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf("%w: duration: must be positive", ErrInvalidSuite)
+	}
+
+	return duration, nil
+}
+
+// scheduleStop function stop given run once it's deadline passed
+// run without deadline left untouched, passed deadline stop the run right away
+func (s *Service) scheduleStop(run *Run) {
+	if run.Deadline == nil || run.IsFinished() {
+		return
+	}
+
+	var (
+		runID = run.ID
+		wait  = time.Until(*run.Deadline)
+	)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if timer, ok := s.deadlines[runID]; ok {
+		timer.Stop()
+	}
+
+	s.deadlines[runID] = time.AfterFunc(wait, func() {
+		s.mu.Lock()
+		delete(s.deadlines, runID)
+		s.mu.Unlock()
+
+		// failed teardown already recorded on the run
+		if _, err := s.StopSuites(runID); err != nil {
+			log.Printf("fail to stop run %s on it's deadline: %s", runID, err)
+		}
+	})
+}
+
+// cancelScheduledStop function cancel scheduled stop of given run
+func (s *Service) cancelScheduledStop(runID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if timer, ok := s.deadlines[runID]; ok {
+		timer.Stop()
+		delete(s.deadlines, runID)
+	}
+}
+
+// ResumeDeadlines function schedule stop of every unfinished run that has deadline
+// meant to be called on startup, so no run outlive it's deadline after restart
+// returning number of scheduled runs
+func (s *Service) ResumeDeadlines() (int, error) {
+	runs, err := s.runStore.GetRuns()
+	if err != nil {
+		return 0, err
+	}
+
+	var scheduled int
+	for i := range runs {
+		if runs[i].Deadline == nil || runs[i].IsFinished() {
+			continue
+		}
+
+		s.scheduleStop(&runs[i])
+		scheduled++
+	}
+
+	return scheduled, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidResource returned when suite file resource can't be loaded
+	// into k8s object, eg : malformed value or unknown kind
+	ErrInvalidResource = errors.New("invalid file resource")
+	// ErrInvalidSuite returned when suite definition is malformed
+	ErrInvalidSuite = errors.New("invalid suite")
+	// ErrRunNotFound returned by run store when run with given id doesn't exist
+	ErrRunNotFound = errors.New("run not found")
+	// ErrTargetLocked matched by every TargetLockedError, use errors.Is to check it
+	ErrTargetLocked = errors.New("target locked")
+	// ErrLockLost returned by run store when renewed or released target lock
+	// no longer held by the run, eg: it expired and acquired by other run
+	ErrLockLost = errors.New("target lock lost")
+	// ErrRunStopped returned when run stopped while it's still being started
+	ErrRunStopped = errors.New("run stopped")
+)
+
+// TargetLockedError struct returned when chaos target already
+// locked by other run, hold the target and run holding it
+type TargetLockedError struct {
+	Target      string
+	HolderRunID string
+}
+
+// Error function return locked target and it's holder
+func (e *TargetLockedError) Error() string {
+	return fmt.Sprintf("%s: %s held by run %s", ErrTargetLocked, e.Target, e.HolderRunID)
+}
+
+// Is function report target locked error as ErrTargetLocked
+func (e *TargetLockedError) Is(target error) bool {
+	return target == ErrTargetLocked
+}
+
+// RollbackError struct returned when run failed and it's created
+// resources rolled back, hold the original failure and every cleanup failure
+type RollbackError struct {
+	Cause         error
+	CleanupErrors []error
+}
+
+// Error function return original failure followed by cleanup failures
+func (e *RollbackError) Error() string {
+	if len(e.CleanupErrors) == 0 {
+		return fmt.Sprintf("%s (created resources rolled back)", e.Cause)
+	}
+
+	cleanupErrs := make([]string, 0, len(e.CleanupErrors))
+	for _, err := range e.CleanupErrors {
+		cleanupErrs = append(cleanupErrs, err.Error())
+	}
+
+	return fmt.Sprintf("%s (rollback failed: %s)", e.Cause, strings.Join(cleanupErrs, "; "))
+}
+
+// Unwrap function return original failure
+func (e *RollbackError) Unwrap() error {
+	return e.Cause
+}
+
+// TeardownError struct returned when some created resources
+// fail to be removed, hold the full teardown report
+type TeardownError struct {
+	Report TeardownReport
+}
+
+// Error function return every failed resource with it's failure
+func (e *TeardownError) Error() string {
+	failed := make([]string, 0, len(e.Report.Failed))
+	for _, f := range e.Report.Failed {
+		failed = append(failed, fmt.Sprintf("%s %s: %s", f.Kind, f.Name, f.Error))
+	}
+
+	total := len(e.Report.Removed) + len(e.Report.Absent) + len(e.Report.Failed)
+
+	return fmt.Sprintf("fail to remove %d of %d resources: %s", len(e.Report.Failed), total, strings.Join(failed, "; "))
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/faruqisan/resilia/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// GarbageRunFinished is reason of object whose run already finished
+	GarbageRunFinished GarbageReason = "run finished"
+	// GarbageRunMissing is reason of object whose run doesn't exist on run store
+	GarbageRunMissing GarbageReason = "run missing"
+	// GarbageRunExpired is reason of object whose run still not finished
+	// after it's deadline or GCOptions.MaxAge, eg: resilia crashed in the middle of the run
+	GarbageRunExpired GarbageReason = "run expired"
+)
+
+type (
+	// GarbageReason type define why object considered as garbage
+	GarbageReason string
+
+	// GCOptions struct define garbage collection behaviour
+	GCOptions struct {
+		// DryRun only list garbage objects without deleting them
+		DryRun bool
+		// MaxAge unfinished run started longer than this considered expired
+		// zero value never expire unfinished run
+		MaxAge time.Duration
+	}
+
+	// Garbage struct define orphaned object created by resilia
+	Garbage struct {
+		Object  string        `json:"object"` // kube.ObjectRef string of the object
+		RunID   string        `json:"run_id"`
+		SuiteID string        `json:"suite_id"`
+		Reason  GarbageReason `json:"reason"`
+		Error   string        `json:"error,omitempty"`
+	}
+
+	// GCReport struct hold result of garbage collection
+	// on dry run every garbage listed as collected without being deleted
+	GCReport struct {
+		DryRun    bool      `json:"dry_run"`
+		Collected []Garbage `json:"collected"`
+		Failed    []Garbage `json:"failed"`
+	}
+)
+
+// CollectGarbage function delete objects labelled by resilia using the same run store
+// whose run is finished, missing from run store or expired, expired run stopped first
+// so it's status updated, objects of run that can't be looked up kept, store outage
+// never lead to deletion, objects of failed run with rollback disabled kept until
+// the run removed from run store
+func (s *Service) CollectGarbage(opts GCOptions) (GCReport, error) {
+
+	report := GCReport{DryRun: opts.DryRun}
+
+	selector, err := s.managedSelector()
+	if err != nil {
+		return report, err
+	}
+
+	objs, err := s.kubeEngine.ListObjects(selector)
+	if err != nil {
+		return report, err
+	}
+
+	var (
+		reasons = make(map[string]GarbageReason)
+		stopped = make(map[string]bool)
+	)
+
+	for _, obj := range objs {
+		var (
+			labels = obj.GetLabels()
+			runID  = labels[LabelRunID]
+		)
+
+		reason, ok := reasons[runID]
+		if !ok {
+			reason, err = s.garbageReason(runID, opts.MaxAge)
+			if err != nil {
+				return report, err
+			}
+			reasons[runID] = reason
+		}
+
+		if reason == "" {
+			continue
+		}
+
+		garbage := Garbage{
+			Object:  objectRef(obj).String(),
+			RunID:   runID,
+			SuiteID: labels[LabelSuiteID],
+			Reason:  reason,
+		}
+
+		if opts.DryRun {
+			report.Collected = append(report.Collected, garbage)
+			continue
+		}
+
+		// stop expired run once, so it's marked as finished and
+		// it's tracked resources terminated in the right order
+		if reason == GarbageRunExpired && !stopped[runID] {
+			stopped[runID] = true
+			var teardownErr *TeardownError
+			if _, err := s.StopSuites(runID); err != nil && !errors.As(err, &teardownErr) {
+				garbage.Error = err.Error()
+				report.Failed = append(report.Failed, garbage)
+				continue
+			}
+		}
+
+		if err := s.kubeEngine.Delete(obj.GroupVersionKind(), obj.GetName()); err != nil && !apierrors.IsNotFound(err) {
+			garbage.Error = err.Error()
+			report.Failed = append(report.Failed, garbage)
+			continue
+		}
+
+		report.Collected = append(report.Collected, garbage)
+	}
+
+	return report, nil
+}
+
+// garbageReason function return why objects of given run are garbage
+// empty reason returned when run still alive
+func (s *Service) garbageReason(runID string, maxAge time.Duration) (GarbageReason, error) {
+	if runID == "" {
+		return GarbageRunMissing, nil
+	}
+
+	run, err := s.runStore.FindRun(runID)
+	if errors.Is(err, ErrRunNotFound) {
+		return GarbageRunMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case run.Status == RunStatusFailed && run.DisableRollback:
+		// resources kept on purpose for debugging
+		return "", nil
+	case run.IsFinished():
+		return GarbageRunFinished, nil
+	case run.Deadline != nil && time.Now().After(*run.Deadline):
+		return GarbageRunExpired, nil
+	case maxAge > 0 && time.Since(run.StartedAt) > maxAge:
+		return GarbageRunExpired, nil
+	}
+
+	return "", nil
+}
+
+// objectRef function return object ref of given object
+func objectRef(obj unstructured.Unstructured) kube.ObjectRef {
+	return kube.ObjectRef{
+		GVK:  obj.GroupVersionKind(),
+		Name: obj.GetName(),
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// LabelRunID is label key hold id of run that created the object
+	LabelRunID = "resilia.io/run-id"
+	// LabelSuiteID is label key hold id of suite that created the object
+	LabelSuiteID = "resilia.io/suite-id"
+	// LabelStoreID is label key hold id of run store of the run that created the object
+	// so resilia using other store never consider the object as it's garbage
+	LabelStoreID = "resilia.io/store-id"
+	// LabelManaged is label key mark object as created by resilia
+	// resilia own key, so managed-by label of user's object left untouched
+	LabelManaged = "resilia.io/managed"
+	// Managed is value of LabelManaged on object created by resilia
+	Managed = "true"
+)
+
+// getStoreID function return id of run store, looked up once
+func (s *Service) getStoreID() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.storeID != "" {
+		return s.storeID, nil
+	}
+
+	id, err := s.runStore.StoreID()
+	if err != nil {
+		return "", err
+	}
+
+	s.storeID = id
+	return id, nil
+}
+
+// managedSelector function return label selector match every object
+// created by resilia using the same run store
+func (s *Service) managedSelector() (string, error) {
+	storeID, err := s.getStoreID()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s=%s,%s=%s", LabelManaged, Managed, LabelStoreID, storeID), nil
+}
+
+// runLabels function return labels put on every object created by given run
+func (s *Service) runLabels(run *Run) (map[string]string, error) {
+	storeID, err := s.getStoreID()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		LabelRunID:   run.ID,
+		LabelSuiteID: run.SuiteID,
+		LabelStoreID: storeID,
+		LabelManaged: Managed,
+	}, nil
+}
+
+// setLabels function add given labels into object labels
+func setLabels(obj metav1.Object, labels map[string]string) {
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = make(map[string]string, len(labels))
+	}
+
+	for k, v := range labels {
+		objLabels[k] = v
+	}
+
+	obj.SetLabels(objLabels)
+}
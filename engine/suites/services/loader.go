@@ -0,0 +1,97 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/faruqisan/resilia/pkg/kube"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// LoadFileResources function turn manifest file, or every manifest file
+// inside directory (and it's sub directories), into suite file resources
+// each yaml document on the file become it's own file resource
+func (s *Service) LoadFileResources(path string) ([]FileResource, error) {
+
+	var resources []FileResource
+
+	files, err := kube.ManifestFiles(path)
+	if err != nil {
+		return resources, err
+	}
+
+	for _, file := range files {
+		fileResources, err := s.loadFileResourcesFromPath(file)
+		if err != nil {
+			return resources, err
+		}
+
+		resources = append(resources, fileResources...)
+	}
+
+	return resources, nil
+}
+
+// LoadFileResourcesFromBytes function turn readed manifest file
+// into suite file resources, one for each yaml document
+func (s *Service) LoadFileResourcesFromBytes(file []byte) ([]FileResource, error) {
+
+	var resources []FileResource
+
+	objs, err := s.kubeEngine.LoadObjectsFromFile(file)
+	if err != nil {
+		return resources, fmt.Errorf("%w: %s", ErrInvalidResource, err)
+	}
+
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return resources, fmt.Errorf("%w: %s", ErrInvalidResource, err)
+		}
+
+		value, err := json.Marshal(obj)
+		if err != nil {
+			return resources, err
+		}
+
+		resources = append(resources, FileResource{
+			Name:  accessor.GetName(),
+			Kind:  kindOf(obj),
+			Value: string(value),
+		})
+	}
+
+	return resources, nil
+}
+
+func (s *Service) loadFileResourcesFromPath(path string) ([]FileResource, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := s.LoadFileResourcesFromBytes(file)
+	if err != nil {
+		return resources, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return resources, nil
+}
+
+// kindOf function return suite kind of k8s object
+// object without dedicated kind applied as generic manifest
+func kindOf(obj runtime.Object) KubeKind {
+	switch obj.(type) {
+	case *appsv1.Deployment:
+		return KindDeployment
+	case *corev1.Service:
+		return KindService
+	case *appsv1.DaemonSet:
+		return KindDaemonSet
+	}
+	return KindManifest
+}
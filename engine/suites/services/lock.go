@@ -0,0 +1,287 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/faruqisan/resilia/pkg/pumba"
+)
+
+const (
+	// lockTTL is how long target lock held without being renewed
+	// so lock of run whose resilia died released on it's own
+	lockTTL = time.Minute
+	// lockRenewInterval is interval of target locks renewal while run alive
+	lockRenewInterval = lockTTL / 3
+)
+
+type (
+	// TargetLock struct define exclusive lock of chaos target held by run
+	TargetLock struct {
+		Target string `json:"target"` // namespace and workload or pod of chaos targets, eg : default/deployment/redis
+		Token  int64  `json:"token"`  // fencing token, increased on every acquisition of the target
+	}
+)
+
+// lockKeys function return lock keys of given pumba worker target, the workload
+// named by the target along with every pod it match at the moment, so targets
+// hitting the same pods conflict, eg : deployment and label selector matching it's pods
+func (s *Service) lockKeys(target pumba.Target) ([]string, error) {
+	namespace, pods, err := s.pumbaEngine.TargetPods(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var workload string
+	switch {
+	case target.Pod != "":
+		workload = "pod/" + target.Pod
+	case target.Deployment != "":
+		workload = "deployment/" + target.Deployment
+	case target.LabelSelector != "":
+		workload = "selector/" + target.LabelSelector
+	default:
+		workload = "namespace"
+	}
+
+	targets := []string{namespace + "/" + workload}
+	for _, pod := range pods {
+		targets = append(targets, namespace+"/pod/"+pod)
+	}
+
+	return targets, nil
+}
+
+// lockTargets function lock every target of suite's pumba workers for given run
+// meant to be called once per run right before it's chaos injected
+// it's all or nothing, acquired locks released when any target held by other run
+// returned error is *TargetLockedError in that case
+func (s *Service) lockTargets(suite *Model, run *Run) error {
+	var (
+		seen    = make(map[string]bool)
+		targets []string
+	)
+
+	for _, spec := range suite.PumbaWorkers {
+		keys, err := s.lockKeys(spec.Target)
+		if err != nil {
+			return err
+		}
+
+		for _, target := range keys {
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	// same acquisition order on every run
+	sort.Strings(targets)
+
+	var acquired []TargetLock
+	for _, target := range targets {
+		token, err := s.runStore.AcquireLock(target, run.ID, lockTTL)
+		if err != nil {
+			s.releaseLocks(run.ID, acquired)
+			return err
+		}
+		acquired = append(acquired, TargetLock{Target: target, Token: token})
+	}
+
+	run.Locks = acquired
+	s.keepLocks(run)
+
+	// never bring back run stopped while it's targets locked
+	s.stopMu.Lock()
+	defer s.stopMu.Unlock()
+
+	if err := s.ensureRunActive(run); err != nil {
+		return err
+	}
+
+	return s.runStore.SaveRun(*run)
+}
+
+// unlockTargets function release every target lock of given run
+// it's best effort, lock failed to be released expire on it's own
+func (s *Service) unlockTargets(run *Run) {
+	s.stopRenewal(run.ID)
+	s.releaseLocks(run.ID, run.Locks)
+}
+
+// releaseLocks function release given locks held by given run
+func (s *Service) releaseLocks(runID string, locks []TargetLock) {
+	for _, lock := range locks {
+		err := s.runStore.ReleaseLock(lock, runID)
+		if err != nil && !errors.Is(err, ErrLockLost) {
+			log.Printf("fail to release lock %s of run %s: %s", lock.Target, runID, err)
+		}
+	}
+}
+
+// keepLocks function renew target locks of given run until they're released
+func (s *Service) keepLocks(run *Run) {
+	if len(run.Locks) == 0 {
+		return
+	}
+
+	var (
+		runID = run.ID
+		locks = append([]TargetLock(nil), run.Locks...)
+		stop  = make(chan struct{})
+	)
+
+	s.mu.Lock()
+	s.renewals[runID] = stop
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(lockRenewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			for _, lock := range locks {
+				err := s.runStore.RenewLock(lock, runID, lockTTL)
+				switch {
+				case errors.Is(err, ErrLockLost):
+					// other run may inject chaos on the target by now
+					s.abortRun(runID, fmt.Errorf("%w: %s", ErrLockLost, lock.Target))
+					return
+				case err != nil:
+					log.Printf("fail to renew lock %s of run %s: %s", lock.Target, runID, err)
+				}
+			}
+		}
+	}()
+}
+
+// abortRun function stop chaos of run whose target lock lost
+// and mark it as failed, run still being started rolled back by it's start
+func (s *Service) abortRun(runID string, cause error) {
+	log.Printf("aborting run %s: %s", runID, cause)
+
+	if _, err := s.stopRun(runID, cause); err != nil && !errors.Is(err, ErrLockLost) {
+		log.Printf("fail to abort run %s: %s", runID, err)
+	}
+}
+
+// ensureLocksHeld function check every target lock still held by given run
+// store compare lock's fencing token, so lock taken over by other run is lost
+// error wrapping ErrLockLost returned in that case
+func (s *Service) ensureLocksHeld(run *Run) error {
+	for _, lock := range run.Locks {
+		err := s.runStore.RenewLock(lock, run.ID, lockTTL)
+		if errors.Is(err, ErrLockLost) {
+			return fmt.Errorf("%w: %s", ErrLockLost, lock.Target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopRenewal function stop target locks renewal of given run
+func (s *Service) stopRenewal(runID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stop, ok := s.renewals[runID]; ok {
+		close(stop)
+		delete(s.renewals, runID)
+	}
+}
+
+// ResumeLocks function take back and keep renewing target locks of every unfinished run
+// locks expired while no resilia renewing them acquired again, run whose target
+// taken by other run meanwhile aborted, meant to be called on startup along with
+// ResumeDeadlines before accepting new runs, returning number of runs whose locks renewed
+func (s *Service) ResumeLocks() (int, error) {
+	runs, err := s.runStore.GetRuns()
+	if err != nil {
+		return 0, err
+	}
+
+	var resumed int
+	for i := range runs {
+		if len(runs[i].Locks) == 0 || runs[i].IsFinished() {
+			continue
+		}
+
+		s.stopRenewal(runs[i].ID)
+
+		err := s.retakeLocks(&runs[i])
+
+		var lockedErr *TargetLockedError
+		if errors.As(err, &lockedErr) {
+			s.abortRun(runs[i].ID, fmt.Errorf("%w: %s taken by run %s", ErrLockLost, lockedErr.Target, lockedErr.HolderRunID))
+			continue
+		}
+		if err != nil {
+			return resumed, err
+		}
+
+		s.keepLocks(&runs[i])
+		resumed++
+	}
+
+	return resumed, nil
+}
+
+// retakeLocks function renew target locks of given run, acquiring again
+// the expired ones and store their new fencing token on the run
+// returned error is *TargetLockedError when other run hold the target
+// locks taken back released on failure, since their token never stored
+func (s *Service) retakeLocks(run *Run) error {
+	var retaken []TargetLock
+	for i, lock := range run.Locks {
+		err := s.runStore.RenewLock(lock, run.ID, lockTTL)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrLockLost) {
+			s.releaseLocks(run.ID, retaken)
+			return err
+		}
+
+		token, err := s.runStore.AcquireLock(lock.Target, run.ID, lockTTL)
+		if err != nil {
+			s.releaseLocks(run.ID, retaken)
+			return err
+		}
+		run.Locks[i].Token = token
+		retaken = append(retaken, run.Locks[i])
+	}
+
+	if len(retaken) == 0 {
+		return nil
+	}
+
+	s.stopMu.Lock()
+	defer s.stopMu.Unlock()
+
+	stored, err := s.runStore.FindRun(run.ID)
+	if err == nil {
+		stored.Locks = run.Locks
+		err = s.runStore.SaveRun(stored)
+	}
+	if err != nil {
+		s.releaseLocks(run.ID, retaken)
+	}
+
+	return err
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"time"
+
+	"github.com/faruqisan/resilia/pkg/kube"
+)
+
+// waitResourcesReady function block until every applied workload ready
+// resource that isn't a workload (eg : service, config map) skipped
+func (s *Service) waitResourcesReady(resources []CreatedResource, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for _, resource := range resources {
+		// timeout shared across all resources
+		remaining := time.Until(deadline)
+
+		var err error
+		switch resource.Kind {
+		case KindDeployment:
+			err = s.kubeEngine.WaitDeploymentReady(resource.Name, remaining)
+		case KindDaemonSet:
+			err = s.kubeEngine.WaitDaemonSetReady(resource.Name, remaining)
+		case KindManifest:
+			err = s.waitManifestReady(resource.Name, remaining)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) waitManifestReady(name string, timeout time.Duration) error {
+	ref, err := kube.ParseObjectRef(name)
+	if err != nil {
+		return err
+	}
+
+	if ref.GVK.Group != "apps" {
+		return nil
+	}
+
+	switch ref.GVK.Kind {
+	case "Deployment":
+		return s.kubeEngine.WaitDeploymentReady(ref.Name, timeout)
+	case "DaemonSet":
+		return s.kubeEngine.WaitDaemonSetReady(ref.Name, timeout)
+	case "StatefulSet":
+		return s.kubeEngine.WaitStatefulSetReady(ref.Name, timeout)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+type (
+	// RunStatus type define state of suite run
+	RunStatus string
+
+	// Run struct define single execution of suite
+	// a suite can be run many times, each run hold it's own created resources
+	Run struct {
+		ID               string                `json:"id"`
+		SuiteID          string                `json:"suite_id"`
+		Status           RunStatus             `json:"status"`
+		Error            string                `json:"error,omitempty"`
+		StartedAt        time.Time             `json:"started_at"`
+		EndedAt          *time.Time            `json:"ended_at,omitempty"`
+		Duration         string                `json:"duration,omitempty"` // max duration of the run, empty when run until stopped
+		Deadline         *time.Time            `json:"deadline,omitempty"` // time the run stopped automatically
+		CreatedResources map[KubeKind][]string `json:"created_resources,omitempty"`
+		Locks            []TargetLock          `json:"locks,omitempty"` // chaos targets locked by the run
+
+		// DisableRollback copied from suite, created resources of failed run
+		// kept on cluster until the run stopped or removed from run store
+		DisableRollback bool `json:"disable_rollback,omitempty"`
+
+		// created hold created resources by creation order
+		// only available on run that started by this process
+		created []CreatedResource
+	}
+
+	// CreatedResource struct define k8s resource created during run
+	CreatedResource struct {
+		Kind KubeKind `json:"kind"`
+		Name string   `json:"name"`
+	}
+)
+
+const (
+	// RunStatusPending is status of run that not started yet
+	RunStatusPending RunStatus = "pending"
+	// RunStatusApplying is status of run while applying suite file resources
+	RunStatusApplying RunStatus = "applying"
+	// RunStatusInjecting is status of run while spawning pumba workers
+	RunStatusInjecting RunStatus = "injecting"
+	// RunStatusRunning is status of run when all chaos already injected
+	RunStatusRunning RunStatus = "running"
+	// RunStatusStopping is status of run while it's created resources terminated
+	RunStatusStopping RunStatus = "stopping"
+	// RunStatusSucceeded is status of run that stopped cleanly
+	RunStatusSucceeded RunStatus = "succeeded"
+	// RunStatusFailed is status of run that failed to start or stop
+	RunStatusFailed RunStatus = "failed"
+	// RunStatusAborted is status of run that stopped before all chaos injected
+	RunStatusAborted RunStatus = "aborted"
+)
+
+// runTransitions define allowed next status of each run status
+var runTransitions = map[RunStatus][]RunStatus{
+	RunStatusPending:   {RunStatusApplying, RunStatusStopping, RunStatusFailed},
+	RunStatusApplying:  {RunStatusInjecting, RunStatusStopping, RunStatusFailed},
+	RunStatusInjecting: {RunStatusRunning, RunStatusStopping, RunStatusFailed},
+	RunStatusRunning:   {RunStatusStopping, RunStatusFailed},
+	RunStatusStopping:  {RunStatusSucceeded, RunStatusFailed, RunStatusAborted},
+}
+
+// IsFinished function return true when run reach it's final status
+func (r *Run) IsFinished() bool {
+	_, ok := runTransitions[r.Status]
+	return !ok
+}
+
+// transition function move run into given status
+// returning error if the move is not allowed from current status
+func (r *Run) transition(to RunStatus) error {
+	for _, next := range runTransitions[r.Status] {
+		if next == to {
+			r.Status = to
+			if r.IsFinished() {
+				now := time.Now()
+				r.EndedAt = &now
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("run %s: can't move from %s to %s", r.ID, r.Status, to)
+}
+
+// addCreatedResource function record created k8s resource on run
+func (r *Run) addCreatedResource(kind KubeKind, name string) {
+	if r.CreatedResources == nil {
+		r.CreatedResources = make(map[KubeKind][]string)
+	}
+	r.CreatedResources[kind] = append(r.CreatedResources[kind], name)
+	r.created = append(r.created, CreatedResource{Kind: kind, Name: name})
+}
+
+// teardownResources function return run's created resources
+// sorted by teardown order
+func (r *Run) teardownResources() []CreatedResource {
+	var resources []CreatedResource
+	for _, kind := range teardownOrder {
+		for _, name := range r.CreatedResources[kind] {
+			resources = append(resources, CreatedResource{Kind: kind, Name: name})
+		}
+	}
+	return resources
+}
+
+// rollbackResources function return run's created resources
+// in reverse creation order, fallback to teardown order
+// when creation order unknown
+func (r *Run) rollbackResources() []CreatedResource {
+	if len(r.created) == 0 {
+		return r.teardownResources()
+	}
+
+	resources := make([]CreatedResource, 0, len(r.created))
+	for i := len(r.created) - 1; i >= 0; i-- {
+		resources = append(resources, r.created[i])
+	}
+	return resources
+}
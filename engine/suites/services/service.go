@@ -2,9 +2,20 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/faruqisan/resilia/pkg/kube"
 	"github.com/faruqisan/resilia/pkg/pumba"
+	"github.com/google/uuid"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type (
@@ -28,6 +39,15 @@ type (
 		CreateService(service *corev1.Service) (string, error)
 		DeleteService(name string) error
 		GetPods() ([]string, error)
+		LoadObjectFromFile(file []byte) (*unstructured.Unstructured, error)
+		LoadObjectsFromFile(file []byte) ([]runtime.Object, error)
+		Apply(obj *unstructured.Unstructured, labels map[string]string) (string, bool, error)
+		Delete(gvk schema.GroupVersionKind, name string) error
+		WaitDeploymentReady(name string, timeout time.Duration) error
+		WaitDaemonSetReady(name string, timeout time.Duration) error
+		WaitStatefulSetReady(name string, timeout time.Duration) error
+		ListObjects(labelSelector string) ([]unstructured.Unstructured, error)
+		Namespace() string
 	}
 
 	// PumbaEngine interface define pumba engine required contract
@@ -35,8 +55,29 @@ type (
 	PumbaEngine interface {
 		NetEm(netEmCommand pumba.NetEmCommands, options pumba.NetEmOptions) pumba.WorkerOptions
 		Pause(options pumba.PauseOptions) pumba.WorkerOptions
-		NewPumbaWorker(target string, interval string, mode pumba.WorkerCommandMode, options ...pumba.WorkerOptions) pumba.Worker
+		Kill(options pumba.KillOptions) pumba.WorkerOptions
+		Stop(options pumba.StopOptions) pumba.WorkerOptions
+		Remove(options pumba.RemoveOptions) pumba.WorkerOptions
+		Stress(options pumba.StressOptions) pumba.WorkerOptions
+		IPTables(options pumba.IPTablesOptions) pumba.WorkerOptions
+		NewPumbaWorker(target pumba.Target, interval string, mode pumba.WorkerCommandMode, options ...pumba.WorkerOptions) pumba.Worker
+		NewPumbaWorkerFromSpec(spec pumba.WorkerSpec) pumba.Worker
 		RunWorker(worker pumba.Worker) (string, error)
+		StopWorker(name string) error
+		TargetPods(target pumba.Target) (string, []string, error)
+	}
+
+	// RunStore interface define suite run storage contract
+	// this is helping us to mock resouces package
+	RunStore interface {
+		SaveRun(run Run) error
+		FindRun(id string) (Run, error)
+		GetRuns() ([]Run, error)
+		AppendCreatedResource(runID string, kind KubeKind, resourceName string) error
+		AcquireLock(target, runID string, ttl time.Duration) (int64, error)
+		RenewLock(lock TargetLock, runID string, ttl time.Duration) error
+		ReleaseLock(lock TargetLock, runID string) error
+		StoreID() (string, error)
 	}
 
 	// KubeKind type define k8s resource kind, eg : deployment, resources or daemon set
@@ -48,24 +89,52 @@ type (
 		ID      string   `json:"id"`
 		SuiteID string   `json:"suite_id"`
 		Name    string   `json:"name"`
-		Kind    KubeKind `json:"king"`
+		Kind    KubeKind `json:"kind"`
 		Value   string   `json:"value"` // k8s deployment.yaml file that parsed into json and stored in database as string
 	}
 
 	// Model struct define test suites
 	// never access property of this struct directly
+	// runtime state of suite execution kept on Run
 	Model struct {
-		ID               string         `json:"id"`
-		Name             string         `json:"name"`
-		Resources        []FileResource `json:"resources,omitempty"`
-		pumbaWorkers     []pumba.Worker
-		CreatedResources map[KubeKind][]string `json:"created_resources,omitempty"`
+		ID        string         `json:"id"`
+		Name      string         `json:"name"`
+		Resources []FileResource `json:"resources,omitempty"`
+
+		// PumbaWorkers spec of pumba workers spawned after resources ready
+		// stored with suite, turned into new worker on every run
+		PumbaWorkers []pumba.WorkerSpec `json:"pumba_workers,omitempty"`
+
+		// DisableRollback keep created resources on cluster when run failed
+		// useful for debugging failing suite, kept resources removed once
+		// the run stopped or expired from run store by garbage collector
+		DisableRollback bool `json:"disable_rollback,omitempty"`
+
+		// ReadyTimeout max duration to wait applied workloads ready
+		// before pumba workers spawned, eg : 90s or 5m (default 5m)
+		ReadyTimeout string `json:"ready_timeout,omitempty"`
+
+		// Duration max duration of suite run, run stopped automatically
+		// once it passed, eg : 30m (default run until stopped)
+		Duration string `json:"duration,omitempty"`
 	}
 
 	// Service struct hold all requirement for suites services
 	Service struct {
 		kubeEngine  KubeEngine
 		pumbaEngine PumbaEngine
+		runStore    RunStore
+
+		// mu guard deadlines, timer of scheduled run stop by run id,
+		// renewals, stop channel of run's target locks renewal by run id
+		// and storeID, id of run store once it's known
+		mu        sync.Mutex
+		deadlines map[string]*time.Timer
+		renewals  map[string]chan struct{}
+		storeID   string
+
+		// stopMu serialize run stop, so scheduled and manual stop never overlap
+		stopMu sync.Mutex
 	}
 )
 
@@ -77,161 +146,479 @@ const (
 	// KindDaemonSet is k8s kind for general daemon set
 	// never put pumba dameon set as this kind
 	KindDaemonSet KubeKind = "daemonset"
-	// KindPumbaDaemonSet is k8s kind for pumba daemon set
-	KindPumbaDaemonSet KubeKind = "daemonset"
+	// KindManifest is k8s manifest of any kind, eg : config map, secret or stateful set
+	// created resource of this kind named by it's kube.ObjectRef
+	KindManifest KubeKind = "manifest"
+	// KindPumbaDaemonSet is kind for daemon set spawned by pumba worker
+	KindPumbaDaemonSet KubeKind = "pumba-daemonset"
 )
 
+// defaultReadyTimeout is max duration to wait applied workloads ready
+// when suite doesn't define it's own
+const defaultReadyTimeout = 5 * time.Minute
+
 // New function return new service object with setuped requirement
-func New(kubeEngine KubeEngine, pumbaEngine PumbaEngine) *Service {
+func New(kubeEngine KubeEngine, pumbaEngine PumbaEngine, runStore RunStore) *Service {
 	return &Service{
 		kubeEngine:  kubeEngine,
 		pumbaEngine: pumbaEngine,
+		runStore:    runStore,
+		deadlines:   make(map[string]*time.Timer),
+		renewals:    make(map[string]chan struct{}),
 	}
 }
 
-// Create function create a new suite model and store it to database
+// Create function create a new suite model
 func (s *Service) Create(id string, name string) *Model {
 	return &Model{
-		ID:               id,
-		Name:             name,
-		CreatedResources: make(map[KubeKind][]string),
+		ID:   id,
+		Name: name,
+	}
+}
+
+// NewModel function return a new suite model with given file resources
+func (s *Service) NewModel(id string, name string, resources []FileResource) *Model {
+	m := s.Create(id, name)
+	m.Resources = resources
+	return m
+}
+
+// UnmarshalJSON function decode file resource, accepting legacy king key
+// written by older resilia as it's kind, so stored suites keep working
+func (r *FileResource) UnmarshalJSON(data []byte) error {
+	type fileResource FileResource
+
+	aux := struct {
+		*fileResource
+		King KubeKind `json:"king"`
+	}{fileResource: (*fileResource)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if r.Kind == "" {
+		r.Kind = aux.King
 	}
+
+	return nil
 }
 
 // AddPumbaWorker function add pumba worker into suite
 func (m *Model) AddPumbaWorker(worker pumba.Worker) {
-	m.pumbaWorkers = append(m.pumbaWorkers, worker)
+	m.PumbaWorkers = append(m.PumbaWorkers, worker.Spec())
+}
+
+// readyTimeout function return parsed suite ready timeout
+func (m *Model) readyTimeout() (time.Duration, error) {
+	if m.ReadyTimeout == "" {
+		return defaultReadyTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(m.ReadyTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("%w: ready_timeout: %s", ErrInvalidSuite, err)
+	}
+
+	return timeout, nil
+}
+
+// pumbaWorkers function return new pumba workers from suite's worker specs
+// returning error when any of them invalid
+func (s *Service) pumbaWorkers(suite *Model) ([]pumba.Worker, error) {
+	workers := make([]pumba.Worker, 0, len(suite.PumbaWorkers))
+	for i, spec := range suite.PumbaWorkers {
+		worker := s.pumbaEngine.NewPumbaWorkerFromSpec(spec)
+		if err := worker.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: pumba_workers[%d]: %s", ErrInvalidSuite, i, err)
+		}
+		workers = append(workers, worker)
+	}
+	return workers, nil
+}
+
+// NewRun function create a pending run of given suite and store it
+// run of suite with duration get it's deadline since it's started
+// invalid suite rejected before anything touch the cluster
+func (s *Service) NewRun(suite *Model) (*Run, error) {
+	if _, err := s.pumbaWorkers(suite); err != nil {
+		return nil, err
+	}
+
+	if _, err := suite.readyTimeout(); err != nil {
+		return nil, err
+	}
+
+	duration, err := suite.duration()
+	if err != nil {
+		return nil, err
+	}
+
+	run := &Run{
+		ID:              uuid.New().String(),
+		SuiteID:         suite.ID,
+		Status:          RunStatusPending,
+		StartedAt:       time.Now(),
+		DisableRollback: suite.DisableRollback,
+	}
+
+	if duration > 0 {
+		deadline := run.StartedAt.Add(duration)
+		run.Duration = duration.String()
+		run.Deadline = &deadline
+	}
+
+	return run, s.runStore.SaveRun(*run)
+}
+
+// StartRun function run given suite from applying it's file resources
+// until all of it's pumba workers spawned, returning the run
+func (s *Service) StartRun(suite *Model) (*Run, error) {
+	run, err := s.NewRun(suite)
+	if err != nil {
+		return run, err
+	}
+
+	return run, s.StartPendingRun(suite, run)
+}
+
+// StartPendingRun function start given pending run of suite created by NewRun
+// run stopped meanwhile roll back what it created and return ErrRunStopped,
+// so caller knowing run id can cancel it while it's being started
+func (s *Service) StartPendingRun(suite *Model, run *Run) error {
+	if err := s.RunSuiteFileResources(suite, run); err != nil {
+		return err
+	}
+
+	return s.RunSuitePumbaWorkers(suite, run)
+}
+
+// FindRun function return stored run with given id
+func (s *Service) FindRun(id string) (Run, error) {
+	return s.runStore.FindRun(id)
+}
+
+// GetRuns function return all stored runs
+func (s *Service) GetRuns() ([]Run, error) {
+	return s.runStore.GetRuns()
 }
 
 // RunSuiteFileResources function run given suite model's only
 // file resource, you can add pumba worker later
-func (s *Service) RunSuiteFileResources(suite *Model) error {
+// on failure all resources created by the run will be rolled back
+// unless suite's rollback disabled
+func (s *Service) RunSuiteFileResources(suite *Model, run *Run) error {
+
+	readyTimeout, err := suite.readyTimeout()
+	if err != nil {
+		return s.rollback(suite, run, err)
+	}
+
+	if err := s.advanceRun(run, RunStatusApplying); err != nil {
+		return s.rollback(suite, run, err)
+	}
+
+	var applied []CreatedResource
 
 	// load all resouce and apply it
 	for _, resource := range suite.Resources {
-		if resource.Kind != KindDaemonSet {
-			name, err := s.applyResourceValue(resource)
-			if err != nil {
-				return err
-			}
+		name, created, err := s.applyResourceValue(run, resource)
+		if err != nil {
+			return s.rollback(suite, run, err)
+		}
 
-			suite.CreatedResources[resource.Kind] = append(suite.CreatedResources[resource.Kind], name)
+		// patched object never removed by the run
+		if created {
+			if err := s.trackCreatedResource(run, resource.Kind, name); err != nil {
+				return s.rollback(suite, run, err)
+			}
 		}
+
+		applied = append(applied, CreatedResource{Kind: resource.Kind, Name: name})
+	}
+
+	// make sure system under test healthy before any chaos injected
+	if err := s.waitResourcesReady(applied, readyTimeout); err != nil {
+		return s.rollback(suite, run, err)
 	}
 
 	return nil
 }
 
 // RunSuitePumbaWorkers function run only suite's pumba worker
-func (s *Service) RunSuitePumbaWorkers(suite *Model) error {
+// on failure all resources created by the run will be rolled back
+// unless suite's rollback disabled
+func (s *Service) RunSuitePumbaWorkers(suite *Model, run *Run) error {
+
+	workers, err := s.pumbaWorkers(suite)
+	if err != nil {
+		return s.rollback(suite, run, err)
+	}
+
+	// no two runs inject chaos into the same target
+	if err := s.lockTargets(suite, run); err != nil {
+		return s.rollback(suite, run, err)
+	}
+
+	if err := s.advanceRun(run, RunStatusInjecting); err != nil {
+		return s.rollback(suite, run, err)
+	}
+
+	labels, err := s.runLabels(run)
+	if err != nil {
+		return s.rollback(suite, run, err)
+	}
+
 	// load all pumba worker
-	for _, worker := range suite.pumbaWorkers {
+	for _, worker := range workers {
+		worker.SetLabels(labels)
+
+		// target might be taken by other run once our lock expired
+		if err := s.ensureLocksHeld(run); err != nil {
+			return s.rollback(suite, run, err)
+		}
+
 		name, err := s.pumbaEngine.RunWorker(worker)
 		if err != nil {
-			return err
+			return s.rollback(suite, run, err)
 		}
-		suite.CreatedResources[KindPumbaDaemonSet] = append(suite.CreatedResources[KindPumbaDaemonSet], name)
+
+		if err := s.trackCreatedResource(run, KindPumbaDaemonSet, name); err != nil {
+			return s.rollback(suite, run, err)
+		}
+	}
+
+	if err := s.advanceRun(run, RunStatusRunning); err != nil {
+		return s.rollback(suite, run, err)
 	}
+
+	// no chaos outlive it's run deadline
+	s.scheduleStop(run)
+
 	return nil
 }
 
-// StopSuites function delete all created resources during suite test
-// this will also clean the created resources
-func (s *Service) StopSuites(suite *Model) error {
-	for kind, createdResources := range suite.CreatedResources {
-		switch kind {
-		case KindDeployment:
-			if err := s.terminateDeployments(createdResources);err != nil {
-				return err
-			}
-		case KindService:
-			if err := s.terminateServices(createdResources); err != nil {
-				return err
-			}
-		case KindDaemonSet:
-			if err := s.terminateDaemonSets(createdResources); err != nil {
-				return err
-			}
+// StopSuites function delete all created resources of given run
+// run that stopped before all chaos injected will be marked as aborted
+// teardown is best effort, every created resource is attempted and resource
+// that already gone counted as removed, so it's safe to call repeatedly
+// returned error is *TeardownError when some resources failed to be removed
+func (s *Service) StopSuites(runID string) (TeardownReport, error) {
+	return s.stopRun(runID, nil)
+}
+
+// stopRun function delete all created resources of given run like StopSuites
+// unfinished run marked as failed with given cause when it's not nil
+func (s *Service) stopRun(runID string, cause error) (TeardownReport, error) {
+	s.stopMu.Lock()
+	defer s.stopMu.Unlock()
+
+	run, err := s.runStore.FindRun(runID)
+	if err != nil {
+		return TeardownReport{}, err
+	}
+
+	// run stopped before it's deadline
+	s.cancelScheduledStop(runID)
+
+	var (
+		finished    = run.IsFinished()
+		finalStatus = RunStatusSucceeded
+	)
+
+	if run.Status != RunStatusRunning {
+		finalStatus = RunStatusAborted
+	}
+
+	if !finished {
+		if err := s.setRunStatus(&run, RunStatusStopping); err != nil {
+			return TeardownReport{}, err
+		}
+	}
+
+	report := s.teardown(run.teardownResources())
+	if len(report.Failed) > 0 {
+		var err error = &TeardownError{Report: report}
+		if finished {
+			return report, err
+		}
+		if cause != nil {
+			err = fmt.Errorf("%v: %w", cause, err)
 		}
+		return report, s.failRun(&run, err)
 	}
+
+	if finished {
+		return report, nil
+	}
+
+	if cause != nil {
+		return report, s.failRun(&run, cause)
+	}
+
+	return report, s.setRunStatus(&run, finalStatus)
+}
+
+// setRunStatus function move run into given status and store it
+// target locks of run released once it's finished
+func (s *Service) setRunStatus(run *Run, status RunStatus) error {
+	if err := run.transition(status); err != nil {
+		return err
+	}
+
+	err := s.runStore.SaveRun(*run)
+
+	if run.IsFinished() {
+		s.unlockTargets(run)
+	}
+
+	return err
+}
+
+// advanceRun function move run being started into given status
+// stored run re-read first, so run stopped meanwhile never brought back
+// returning ErrRunStopped in that case
+func (s *Service) advanceRun(run *Run, status RunStatus) error {
+	s.stopMu.Lock()
+	defer s.stopMu.Unlock()
+
+	if err := s.ensureRunActive(run); err != nil {
+		return err
+	}
+
+	return s.setRunStatus(run, status)
+}
+
+// ensureRunActive function return ErrRunStopped when stored run
+// already stopped or being stopped, caller must hold stopMu
+func (s *Service) ensureRunActive(run *Run) error {
+	stored, err := s.runStore.FindRun(run.ID)
+	if err != nil {
+		return err
+	}
+
+	if stored.IsFinished() || stored.Status == RunStatusStopping {
+		return fmt.Errorf("%w: run %s is %s", ErrRunStopped, run.ID, stored.Status)
+	}
+
 	return nil
 }
 
-func (s *Service) applyResourceValue(resource FileResource) (string, error) {
+// failStartingRun function mark run being started as failed with given cause
+// run stopped meanwhile keep status given by it's stop, only it's locks released
+func (s *Service) failStartingRun(run *Run, cause error) error {
+	s.stopMu.Lock()
+	defer s.stopMu.Unlock()
+
+	if err := s.ensureRunActive(run); errors.Is(err, ErrRunStopped) {
+		s.unlockTargets(run)
+		return cause
+	}
+
+	return s.failRun(run, cause)
+}
+
+// failRun function mark run as failed with given cause
+// returning the cause so caller can return it directly
+func (s *Service) failRun(run *Run, cause error) error {
+	run.Error = cause.Error()
+	if err := s.setRunStatus(run, RunStatusFailed); err != nil {
+		return fmt.Errorf("%w (fail to mark run as failed: %v)", cause, err)
+	}
+	return cause
+}
+
+// trackCreatedResource function record created resource on run and store it
+// so the resource can be terminated later even after restart
+func (s *Service) trackCreatedResource(run *Run, kind KubeKind, name string) error {
+	run.addCreatedResource(kind, name)
+	return s.runStore.AppendCreatedResource(run.ID, kind, name)
+}
+
+// applyResourceValue function create file resource on cluster
+// labelled with run labels, so it can be found by garbage collector
+// returning whether it's created, existing manifest object patched instead
+func (s *Service) applyResourceValue(run *Run, resource FileResource) (string, bool, error) {
 
 	jsonData := []byte(resource.Value)
 
+	labels, err := s.runLabels(run)
+	if err != nil {
+		return "", false, err
+	}
+
+	var name string
 	switch resource.Kind {
 	case KindDeployment:
-		return s.applyDeployment(jsonData)
+		name, err = s.applyDeployment(jsonData, labels)
 	case KindService:
-		return s.applyService(jsonData)
+		name, err = s.applyService(jsonData, labels)
 	case KindDaemonSet:
-		return s.applyDaemonSet(jsonData)
+		name, err = s.applyDaemonSet(jsonData, labels)
+	case KindManifest:
+		return s.applyManifest(jsonData, labels)
+	default:
+		return "", false, fmt.Errorf("%w: unknown kind %q", ErrInvalidResource, resource.Kind)
 	}
 
-	return "", nil
+	// typed kinds only created, existing one fail with already exists
+	return name, err == nil, err
 }
 
-func (s *Service) applyDeployment(value []byte) (string, error) {
+func (s *Service) applyDeployment(value []byte, labels map[string]string) (string, error) {
 
 	dep, err := s.kubeEngine.LoadDeploymentFromFile(value)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %s", ErrInvalidResource, err)
 	}
+	setLabels(dep, labels)
 
 	return s.kubeEngine.CreateDeployment(dep)
 
 }
 
-func (s *Service) applyService(value []byte) (string, error) {
+func (s *Service) applyService(value []byte, labels map[string]string) (string, error) {
 
 	svc, err := s.kubeEngine.LoadServiceFromFile(value)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %s", ErrInvalidResource, err)
 	}
+	setLabels(svc, labels)
 
 	return s.kubeEngine.CreateService(svc)
 
 }
 
-func (s *Service) applyDaemonSet(value []byte) (string, error) {
+func (s *Service) applyDaemonSet(value []byte, labels map[string]string) (string, error) {
 
 	ds, err := s.kubeEngine.LoadDaemonSetFromFile(value)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %s", ErrInvalidResource, err)
 	}
+	setLabels(ds, labels)
 
 	return s.kubeEngine.CreateDaemonSet(ds)
 
 }
 
-func (s *Service) terminateDeployments(createdResources []string) error {
-	for _, createdDeployment := range createdResources {
-		err := s.kubeEngine.DeleteDeployment(createdDeployment)
-		if err != nil {
-			return err
-		}
+// applyManifest function create manifest object labelled with given labels
+// existing object patched without them, it's not owned by the run
+func (s *Service) applyManifest(value []byte, labels map[string]string) (string, bool, error) {
+
+	obj, err := s.kubeEngine.LoadObjectFromFile(value)
+	if err != nil {
+		return "", false, fmt.Errorf("%w: %s", ErrInvalidResource, err)
 	}
-	return nil
-}
 
-func (s *Service) terminateServices(createdResources []string) error {
-	for _, createdService := range createdResources {
-		err := s.kubeEngine.DeleteService(createdService)
-		if err != nil {
-			return err
-		}
+	name, created, err := s.kubeEngine.Apply(obj, labels)
+	if err != nil {
+		return "", false, err
 	}
-	return nil
-}
 
-func (s *Service) terminateDaemonSets(createdResources []string) error {
-	for _, createdDaemonSet := range createdResources {
-		err := s.kubeEngine.DeleteDaemonSet(createdDaemonSet)
-		if err != nil {
-			return err
-		}
+	ref := kube.ObjectRef{
+		GVK:  obj.GroupVersionKind(),
+		Name: name,
 	}
-	return nil
+
+	return ref.String(), created, nil
 }
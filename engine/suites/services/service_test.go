@@ -0,0 +1,636 @@
+package services_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/resouces"
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/pkg/pumba"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type (
+	// fakeKube struct is kube engine backed by in memory cluster
+	// call of method not implemented here panic on nil embedded engine
+	fakeKube struct {
+		services.KubeEngine
+
+		// objects on cluster keyed by kind and name, eg : deployment/api
+		objects map[string]bool
+		// deleted hold removed objects by removal order
+		deleted []string
+		// deleteErr returned on deletion of object with given key
+		deleteErr map[string]error
+		// onCreate called after object with given key created
+		onCreate func(key string)
+	}
+
+	// fakePumba struct is pumba engine spawning workers on fake cluster
+	fakePumba struct {
+		*pumba.Engine
+		kube *fakeKube
+	}
+)
+
+func newFakeKube() *fakeKube {
+	return &fakeKube{
+		objects:   make(map[string]bool),
+		deleteErr: make(map[string]error),
+	}
+}
+
+func (k *fakeKube) create(kind services.KubeKind, name string) (string, error) {
+	key := string(kind) + "/" + name
+	if k.objects[key] {
+		return "", apierrors.NewAlreadyExists(schema.GroupResource{Resource: string(kind)}, name)
+	}
+	k.objects[key] = true
+	if k.onCreate != nil {
+		k.onCreate(key)
+	}
+	return name, nil
+}
+
+func (k *fakeKube) delete(kind services.KubeKind, name string) error {
+	key := string(kind) + "/" + name
+	if err, ok := k.deleteErr[key]; ok {
+		return err
+	}
+	if !k.objects[key] {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: string(kind)}, name)
+	}
+	delete(k.objects, key)
+	k.deleted = append(k.deleted, key)
+	return nil
+}
+
+func (k *fakeKube) LoadDeploymentFromFile(file []byte) (*appsv1.Deployment, error) {
+	var dep appsv1.Deployment
+	return &dep, json.Unmarshal(file, &dep)
+}
+
+func (k *fakeKube) CreateDeployment(dep *appsv1.Deployment) (string, error) {
+	return k.create(services.KindDeployment, dep.Name)
+}
+
+func (k *fakeKube) DeleteDeployment(name string) error {
+	return k.delete(services.KindDeployment, name)
+}
+
+func (k *fakeKube) WaitDeploymentReady(name string, timeout time.Duration) error {
+	return nil
+}
+
+func (k *fakeKube) LoadDaemonSetFromFile(file []byte) (*appsv1.DaemonSet, error) {
+	var ds appsv1.DaemonSet
+	return &ds, json.Unmarshal(file, &ds)
+}
+
+func (k *fakeKube) CreateDaemonSet(ds *appsv1.DaemonSet) (string, error) {
+	return k.create(services.KindDaemonSet, ds.Name)
+}
+
+func (k *fakeKube) DeleteDaemonSet(name string) error {
+	return k.delete(services.KindDaemonSet, name)
+}
+
+func (k *fakeKube) WaitDaemonSetReady(name string, timeout time.Duration) error {
+	return nil
+}
+
+func (k *fakeKube) Namespace() string {
+	return "default"
+}
+
+func (p *fakePumba) RunWorker(worker pumba.Worker) (string, error) {
+	return p.kube.create(services.KindPumbaDaemonSet, "pumba-"+worker.GetID())
+}
+
+func (p *fakePumba) StopWorker(name string) error {
+	return p.kube.delete(services.KindPumbaDaemonSet, name)
+}
+
+// TargetPods method return two pods of deployment targeted, pods of
+// deployment x matched by label selector app=x as well
+func (p *fakePumba) TargetPods(target pumba.Target) (string, []string, error) {
+	app := target.Deployment
+	switch {
+	case target.Pod != "":
+		return "default", []string{target.Pod}, nil
+	case target.LabelSelector != "":
+		app = strings.TrimPrefix(target.LabelSelector, "app=")
+	}
+	return "default", []string{app + "-0", app + "-1"}, nil
+}
+
+func newTestService() (*services.Service, *fakeKube, *resouces.MemoryStore) {
+	var (
+		kube  = newFakeKube()
+		store = resouces.NewMemory()
+	)
+	return services.New(kube, &fakePumba{Engine: pumba.New(nil), kube: kube}, store), kube, store
+}
+
+func objectValue(t *testing.T, name string) string {
+	value, err := json.Marshal(metav1.ObjectMeta{Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return `{"metadata":` + string(value) + `}`
+}
+
+func newTestSuite(t *testing.T) *services.Model {
+	return &services.Model{
+		ID:   "suite-1",
+		Name: "suite",
+		Resources: []services.FileResource{
+			{Name: "api", Kind: services.KindDeployment, Value: objectValue(t, "api")},
+			{Name: "agent", Kind: services.KindDaemonSet, Value: objectValue(t, "agent")},
+		},
+		PumbaWorkers: []pumba.WorkerSpec{
+			{
+				Target:   pumba.Target{Deployment: "api"},
+				Interval: "10s",
+				Kill:     &pumba.KillOptions{Signal: "SIGKILL"},
+			},
+		},
+	}
+}
+
+func TestStartRunCreateUserDaemonSet(t *testing.T) {
+	svc, kube, store := newTestService()
+
+	run, err := svc.StartRun(newTestSuite(t))
+	if err != nil {
+		t.Fatalf("StartRun() error = %v", err)
+	}
+
+	stored, err := store.FindRun(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Status != services.RunStatusRunning {
+		t.Errorf("run status = %s, want %s", stored.Status, services.RunStatusRunning)
+	}
+
+	if got := stored.CreatedResources[services.KindDaemonSet]; !reflect.DeepEqual(got, []string{"agent"}) {
+		t.Errorf("created daemon sets = %v, want [agent]", got)
+	}
+
+	workers := stored.CreatedResources[services.KindPumbaDaemonSet]
+	if len(workers) != 1 {
+		t.Fatalf("created pumba daemon sets = %v, want 1", workers)
+	}
+
+	for _, key := range []string{"deployment/api", "daemonset/agent", "pumba-daemonset/" + workers[0]} {
+		if !kube.objects[key] {
+			t.Errorf("%s not created on cluster", key)
+		}
+	}
+}
+
+func TestStartRunAlreadyExists(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		// rolledBack objects created by the run before it failed
+		rolledBack []string
+	}{
+		{
+			name:     "deployment",
+			existing: "deployment/api",
+		},
+		{
+			name:       "daemon set",
+			existing:   "daemonset/agent",
+			rolledBack: []string{"deployment/api"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, kube, store := newTestService()
+			kube.objects[tt.existing] = true
+
+			run, err := svc.StartRun(newTestSuite(t))
+
+			var statusErr *apierrors.StatusError
+			if !errors.As(err, &statusErr) || !apierrors.IsAlreadyExists(statusErr) {
+				t.Fatalf("StartRun() error = %v, want already exists", err)
+			}
+
+			stored, err := store.FindRun(run.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored.Status != services.RunStatusFailed {
+				t.Errorf("run status = %s, want %s", stored.Status, services.RunStatusFailed)
+			}
+
+			// object not created by the run never removed
+			if !kube.objects[tt.existing] {
+				t.Errorf("existing %s removed by rollback", tt.existing)
+			}
+
+			if !reflect.DeepEqual(kube.deleted, tt.rolledBack) {
+				t.Errorf("rolled back = %v, want %v", kube.deleted, tt.rolledBack)
+			}
+		})
+	}
+}
+
+func TestStopSuitesTeardown(t *testing.T) {
+	svc, kube, store := newTestService()
+
+	run, err := svc.StartRun(newTestSuite(t))
+	if err != nil {
+		t.Fatalf("StartRun() error = %v", err)
+	}
+	worker := "pumba-daemonset/" + run.CreatedResources[services.KindPumbaDaemonSet][0]
+
+	report, err := svc.StopSuites(run.ID)
+	if err != nil {
+		t.Fatalf("StopSuites() error = %v", err)
+	}
+
+	// chaos stopped before system under test removed
+	want := []string{worker, "daemonset/agent", "deployment/api"}
+	if !reflect.DeepEqual(kube.deleted, want) {
+		t.Errorf("deleted = %v, want %v", kube.deleted, want)
+	}
+
+	if len(report.Removed) != 3 || len(report.Absent) != 0 || len(report.Failed) != 0 {
+		t.Errorf("report = %+v, want 3 removed", report)
+	}
+
+	stored, err := store.FindRun(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Status != services.RunStatusSucceeded {
+		t.Errorf("run status = %s, want %s", stored.Status, services.RunStatusSucceeded)
+	}
+}
+
+func TestStopSuitesNotFound(t *testing.T) {
+	tests := []struct {
+		name    string
+		removed []string
+	}{
+		{
+			name:    "user daemon set",
+			removed: []string{"daemonset/agent"},
+		},
+		{
+			name:    "pumba daemon set",
+			removed: []string{"pumba-daemonset/*"},
+		},
+		{
+			name:    "every resource",
+			removed: []string{"pumba-daemonset/*", "daemonset/agent", "deployment/api"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, kube, store := newTestService()
+
+			run, err := svc.StartRun(newTestSuite(t))
+			if err != nil {
+				t.Fatalf("StartRun() error = %v", err)
+			}
+			worker := "pumba-daemonset/" + run.CreatedResources[services.KindPumbaDaemonSet][0]
+
+			for _, key := range tt.removed {
+				if key == "pumba-daemonset/*" {
+					key = worker
+				}
+				delete(kube.objects, key)
+			}
+
+			report, err := svc.StopSuites(run.ID)
+			if err != nil {
+				t.Fatalf("StopSuites() error = %v", err)
+			}
+
+			if len(report.Absent) != len(tt.removed) || len(report.Removed) != 3-len(tt.removed) {
+				t.Errorf("report = %+v, want %d absent", report, len(tt.removed))
+			}
+
+			stored, err := store.FindRun(run.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored.Status != services.RunStatusSucceeded {
+				t.Errorf("run status = %s, want %s", stored.Status, services.RunStatusSucceeded)
+			}
+
+			// stopping again find every resource already gone
+			report, err = svc.StopSuites(run.ID)
+			if err != nil {
+				t.Fatalf("second StopSuites() error = %v", err)
+			}
+
+			if len(report.Absent) != 3 {
+				t.Errorf("second report = %+v, want 3 absent", report)
+			}
+		})
+	}
+}
+
+func TestStopSuitesFailure(t *testing.T) {
+	svc, kube, store := newTestService()
+
+	run, err := svc.StartRun(newTestSuite(t))
+	if err != nil {
+		t.Fatalf("StartRun() error = %v", err)
+	}
+	kube.deleteErr["daemonset/agent"] = errors.New("connection refused")
+
+	report, err := svc.StopSuites(run.ID)
+
+	var teardownErr *services.TeardownError
+	if !errors.As(err, &teardownErr) {
+		t.Fatalf("StopSuites() error = %v, want *TeardownError", err)
+	}
+
+	if len(report.Failed) != 1 || report.Failed[0].Name != "agent" {
+		t.Errorf("failed = %+v, want daemon set agent", report.Failed)
+	}
+
+	// teardown keep going after failure
+	if kube.objects["deployment/api"] {
+		t.Error("deployment/api not removed after daemon set failure")
+	}
+
+	stored, err := store.FindRun(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Status != services.RunStatusFailed {
+		t.Errorf("run status = %s, want %s", stored.Status, services.RunStatusFailed)
+	}
+}
+
+func TestStartPendingRunStopped(t *testing.T) {
+	svc, kube, store := newTestService()
+	suite := newTestSuite(t)
+
+	run, err := svc.NewRun(suite)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stopped after it's first resource created, before the rest applied
+	var stopErr error
+	kube.onCreate = func(key string) {
+		kube.onCreate = nil
+		_, stopErr = svc.StopSuites(run.ID)
+	}
+
+	err = svc.StartPendingRun(suite, run)
+	if !errors.Is(err, services.ErrRunStopped) {
+		t.Fatalf("StartPendingRun() error = %v, want ErrRunStopped", err)
+	}
+
+	if stopErr != nil {
+		t.Fatalf("StopSuites() error = %v", stopErr)
+	}
+
+	if len(kube.objects) != 0 {
+		t.Errorf("objects left on cluster = %v", kube.objects)
+	}
+
+	stored, err := store.FindRun(run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Status != services.RunStatusAborted {
+		t.Errorf("run status = %s, want %s", stored.Status, services.RunStatusAborted)
+	}
+}
+
+func TestStartRunTargetLocked(t *testing.T) {
+	tests := []struct {
+		name   string
+		target pumba.Target
+		locked bool
+	}{
+		{
+			name:   "same deployment",
+			target: pumba.Target{Deployment: "api"},
+			locked: true,
+		},
+		{
+			name:   "label selector matching deployment pods",
+			target: pumba.Target{LabelSelector: "app=api"},
+			locked: true,
+		},
+		{
+			name:   "pod of deployment",
+			target: pumba.Target{Pod: "api-1"},
+			locked: true,
+		},
+		{
+			name:   "other deployment",
+			target: pumba.Target{Deployment: "web"},
+		},
+		{
+			name:   "other pod",
+			target: pumba.Target{Pod: "web-0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, kube, _ := newTestService()
+
+			run, err := svc.StartRun(newTestSuite(t))
+			if err != nil {
+				t.Fatalf("StartRun() error = %v", err)
+			}
+
+			other := &services.Model{
+				ID:   "suite-2",
+				Name: "other",
+				PumbaWorkers: []pumba.WorkerSpec{
+					{
+						Target:   tt.target,
+						Interval: "10s",
+						Pause:    &pumba.PauseOptions{Duration: "5s"},
+					},
+				},
+			}
+
+			_, err = svc.StartRun(other)
+			if !tt.locked {
+				if err != nil {
+					t.Fatalf("StartRun() error = %v, want target of other workload free", err)
+				}
+				return
+			}
+
+			var lockedErr *services.TargetLockedError
+			if !errors.As(err, &lockedErr) || lockedErr.HolderRunID != run.ID {
+				t.Fatalf("StartRun() error = %v, want target locked by run %s", err, run.ID)
+			}
+
+			var workers int
+			for key := range kube.objects {
+				if strings.HasPrefix(key, string(services.KindPumbaDaemonSet)+"/") {
+					workers++
+				}
+			}
+			if workers != 1 {
+				t.Errorf("pumba daemon sets = %d, want only the one of run holding the lock", workers)
+			}
+
+			if _, err := svc.StopSuites(run.ID); err != nil {
+				t.Fatalf("StopSuites() error = %v", err)
+			}
+
+			if _, err := svc.StartRun(other); err != nil {
+				t.Errorf("StartRun() after lock released error = %v", err)
+			}
+		})
+	}
+}
+
+func TestStartRunLockLost(t *testing.T) {
+	svc, kube, store := newTestService()
+
+	suite := newTestSuite(t)
+	suite.PumbaWorkers = append(suite.PumbaWorkers, pumba.WorkerSpec{
+		Target:   pumba.Target{Deployment: "api"},
+		Interval: "10s",
+		Pause:    &pumba.PauseOptions{Duration: "5s"},
+	})
+
+	// lock expire and taken by other run right after first worker created
+	var runID string
+	kube.onCreate = func(key string) {
+		if !strings.HasPrefix(key, string(services.KindPumbaDaemonSet)+"/") {
+			return
+		}
+
+		runs, err := store.GetRuns()
+		if err != nil || len(runs) != 1 || len(runs[0].Locks) == 0 {
+			t.Fatalf("GetRuns() = %+v, %v, want single run holding locks", runs, err)
+		}
+
+		runID = runs[0].ID
+		if err := store.ReleaseLock(runs[0].Locks[0], runID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.AcquireLock(runs[0].Locks[0].Target, "other-run", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := svc.StartRun(suite)
+	if !errors.Is(err, services.ErrLockLost) {
+		t.Fatalf("StartRun() error = %v, want %v", err, services.ErrLockLost)
+	}
+
+	stored, err := store.FindRun(runID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored.Status != services.RunStatusFailed {
+		t.Errorf("run status = %s, want %s", stored.Status, services.RunStatusFailed)
+	}
+
+	// second worker never created, everything else rolled back
+	if len(kube.objects) != 0 {
+		t.Errorf("objects left on cluster = %v, want none", kube.objects)
+	}
+}
+
+func TestResumeLocks(t *testing.T) {
+	tests := []struct {
+		name string
+		// holder run taking the lock while resilia down, none when it simply expired
+		holder string
+		want   services.RunStatus
+	}{
+		{
+			name: "expired",
+			want: services.RunStatusRunning,
+		},
+		{
+			name:   "taken by other run",
+			holder: "other-run",
+			want:   services.RunStatusFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, kube, store := newTestService()
+
+			run, err := svc.StartRun(newTestSuite(t))
+			if err != nil {
+				t.Fatalf("StartRun() error = %v", err)
+			}
+			lock := run.Locks[0]
+
+			// resilia down long enough for the lock to expire
+			if err := store.ReleaseLock(lock, run.ID); err != nil {
+				t.Fatal(err)
+			}
+			if tt.holder != "" {
+				if _, err := store.AcquireLock(lock.Target, tt.holder, time.Minute); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			restarted := services.New(kube, &fakePumba{Engine: pumba.New(nil), kube: kube}, store)
+			if _, err := restarted.ResumeLocks(); err != nil {
+				t.Fatalf("ResumeLocks() error = %v", err)
+			}
+
+			stored, err := store.FindRun(run.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stored.Status != tt.want {
+				t.Fatalf("run status = %s, want %s", stored.Status, tt.want)
+			}
+
+			if tt.holder != "" {
+				if !strings.Contains(stored.Error, tt.holder) {
+					t.Errorf("run error = %q, want holder %s", stored.Error, tt.holder)
+				}
+				if len(kube.objects) != 0 {
+					t.Errorf("objects left on cluster = %v, want none", kube.objects)
+				}
+				return
+			}
+
+			if len(stored.Locks) != len(run.Locks) || stored.Locks[0].Token <= lock.Token {
+				t.Fatalf("locks = %+v, want lock taken back with newer token than %d", stored.Locks, lock.Token)
+			}
+
+			_, err = store.AcquireLock(lock.Target, "other-run", time.Minute)
+			if !errors.Is(err, services.ErrTargetLocked) {
+				t.Errorf("AcquireLock() error = %v, want target held by resumed run", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/faruqisan/resilia/pkg/kube"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+type (
+	// TeardownReport struct hold result of created resources termination
+	TeardownReport struct {
+		Removed []CreatedResource `json:"removed"`
+		Absent  []CreatedResource `json:"absent"`
+		Failed  []FailedResource  `json:"failed"`
+	}
+
+	// FailedResource struct define created resource that fail to be removed
+	FailedResource struct {
+		CreatedResource
+		Error string `json:"error"`
+		Err   error  `json:"-"`
+	}
+)
+
+// teardownOrder define order of created resource termination
+// pumba workers stopped first so no chaos injected while system under test removed
+var teardownOrder = []KubeKind{
+	KindPumbaDaemonSet,
+	KindService,
+	KindDaemonSet,
+	KindDeployment,
+	KindManifest,
+}
+
+// rollback function terminate all resources created by run in reverse order
+// and mark run as failed, returning error that hold cause and cleanup failures
+// resources of run stopped meanwhile always terminated, it's stop may miss them
+func (s *Service) rollback(suite *Model, run *Run, cause error) error {
+	if suite.DisableRollback && !errors.Is(cause, ErrRunStopped) {
+		return s.failStartingRun(run, cause)
+	}
+
+	var (
+		rollbackErr = &RollbackError{Cause: cause}
+		report      = s.teardown(run.rollbackResources())
+	)
+
+	for _, failed := range report.Failed {
+		rollbackErr.CleanupErrors = append(rollbackErr.CleanupErrors, fmt.Errorf("%s %s: %w", failed.Kind, failed.Name, failed.Err))
+	}
+
+	return s.failStartingRun(run, rollbackErr)
+}
+
+// teardown function terminate all given resources in order
+// it keeps going on failure and treat not found resource as already removed
+func (s *Service) teardown(resources []CreatedResource) TeardownReport {
+	var report TeardownReport
+
+	for _, resource := range resources {
+		err := s.terminateResource(resource)
+		switch {
+		case err == nil:
+			report.Removed = append(report.Removed, resource)
+		case apierrors.IsNotFound(err):
+			report.Absent = append(report.Absent, resource)
+		default:
+			report.Failed = append(report.Failed, FailedResource{
+				CreatedResource: resource,
+				Error:           err.Error(),
+				Err:             err,
+			})
+		}
+	}
+
+	return report
+}
+
+func (s *Service) terminateResource(resource CreatedResource) error {
+	switch resource.Kind {
+	case KindPumbaDaemonSet:
+		return s.pumbaEngine.StopWorker(resource.Name)
+	case KindDeployment:
+		return s.kubeEngine.DeleteDeployment(resource.Name)
+	case KindService:
+		return s.kubeEngine.DeleteService(resource.Name)
+	case KindDaemonSet:
+		return s.kubeEngine.DeleteDaemonSet(resource.Name)
+	case KindManifest:
+		ref, err := kube.ParseObjectRef(resource.Name)
+		if err != nil {
+			return err
+		}
+		return s.kubeEngine.Delete(ref.GVK, ref.Name)
+	}
+	return fmt.Errorf("unknown kind %q", resource.Kind)
+}
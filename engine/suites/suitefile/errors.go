@@ -0,0 +1,139 @@
+package suitefile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Error struct define single schema error of suite document
+	// line is 0 when error can't be pointed to document line
+	Error struct {
+		Line    int    `json:"line,omitempty"`
+		Field   string `json:"field,omitempty"`
+		Message string `json:"message"`
+	}
+
+	// ParseError struct returned when suite document is invalid
+	// hold every schema error of the document
+	ParseError struct {
+		Errors []Error
+	}
+
+	// nodeLines struct find line number of document field
+	nodeLines struct {
+		root *yaml.Node
+	}
+)
+
+// Error function return single schema error, eg : line 4: name: is required
+func (e Error) Error() string {
+	var parts []string
+
+	if e.Line > 0 {
+		parts = append(parts, fmt.Sprintf("line %d", e.Line))
+	}
+
+	if e.Field != "" {
+		parts = append(parts, e.Field)
+	}
+
+	return strings.Join(append(parts, e.Message), ": ")
+}
+
+// Error function return all schema errors in single line
+func (e *ParseError) Error() string {
+	errs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err.Error())
+	}
+	return "invalid suite document: " + strings.Join(errs, "; ")
+}
+
+// Unwrap function make invalid document recognized as invalid suite
+func (e *ParseError) Unwrap() error {
+	return services.ErrInvalidSuite
+}
+
+func (e *ParseError) add(line int, field, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, Error{
+		Line:    line,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// fromYAML function turn yaml decoding error into parse error
+// yaml report each error as "line N: message"
+func (e *ParseError) fromYAML(err error) *ParseError {
+	messages := []string{strings.TrimPrefix(err.Error(), "yaml: ")}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+
+	for _, message := range messages {
+		var line int
+		if strings.HasPrefix(message, "line ") {
+			if i := strings.Index(message, ": "); i > 0 {
+				if n, convErr := strconv.Atoi(message[len("line "):i]); convErr == nil {
+					line, message = n, message[i+2:]
+				}
+			}
+		}
+		e.add(line, "", "%s", message)
+	}
+
+	return e
+}
+
+// of function return line number of given field path, eg : workers[0].netem.delay.time
+// when field doesn't exist on document line of it's closest parent returned
+func (l nodeLines) of(field string) int {
+	var (
+		node = l.root
+		line = node.Line
+	)
+
+	for _, part := range strings.Split(field, ".") {
+		key, index := part, -1
+		if i := strings.Index(part, "["); i > 0 && strings.HasSuffix(part, "]") {
+			key = part[:i]
+			index, _ = strconv.Atoi(part[i+1 : len(part)-1])
+		}
+
+		node = mappingValue(node, key)
+		if node == nil {
+			return line
+		}
+		line = node.Line
+
+		if index >= 0 {
+			if node.Kind != yaml.SequenceNode || index >= len(node.Content) {
+				return line
+			}
+			node = node.Content[index]
+			line = node.Line
+		}
+	}
+
+	return line
+}
+
+// mappingValue function return value node of given key on mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
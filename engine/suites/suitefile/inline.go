@@ -0,0 +1,87 @@
+package suitefile
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/faruqisan/resilia/pkg/kube"
+	"gopkg.in/yaml.v3"
+)
+
+// InlineFile function read suite document from disk and replace every
+// manifest referenced by path with it's inline content, so document
+// can be sent to remote resilia server that can't read local files
+func InlineFile(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, (&ParseError{}).fromYAML(err)
+	}
+
+	if len(root.Content) == 0 {
+		return data, nil
+	}
+
+	manifests := mappingValue(root.Content[0], "manifests")
+	if manifests == nil || manifests.Kind != yaml.SequenceNode {
+		return data, nil
+	}
+
+	for _, manifest := range manifests.Content {
+		if manifest.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for i := 0; i+1 < len(manifest.Content); i += 2 {
+			key, value := manifest.Content[i], manifest.Content[i+1]
+			if key.Value != "path" {
+				continue
+			}
+
+			manifestPath := value.Value
+			if !filepath.IsAbs(manifestPath) {
+				manifestPath = filepath.Join(filepath.Dir(path), manifestPath)
+			}
+
+			content, err := readManifests(manifestPath)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", value.Line, err)
+			}
+
+			key.Value = "inline"
+			value.Value = content
+			value.Tag = "!!str"
+			value.Style = yaml.LiteralStyle
+		}
+	}
+
+	return yaml.Marshal(&root)
+}
+
+// readManifests function return content of manifest file, or every manifest
+// file inside directory joined as multi document yaml
+func readManifests(path string) (string, error) {
+	files, err := kube.ManifestFiles(path)
+	if err != nil {
+		return "", err
+	}
+
+	var docs []string
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+
+		docs = append(docs, string(bytes.TrimSpace(content)))
+	}
+
+	return strings.Join(docs, "\n---\n") + "\n", nil
+}
@@ -0,0 +1,273 @@
+// Package suitefile hold declarative suite document format, a versioned
+// yaml (or json) document that turned into ready to run suite model
+package suitefile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/pkg/pumba"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is current suite document version
+const Version = "resilia/v1"
+
+type (
+	// ResourceLoader interface define contract to turn manifest into suite file resources
+	// this is helping us to mock services package
+	ResourceLoader interface {
+		LoadFileResources(path string) ([]services.FileResource, error)
+		LoadFileResourcesFromBytes(file []byte) ([]services.FileResource, error)
+	}
+
+	// PumbaEngine interface define contract with pumba engine
+	// used to validate document's workers
+	PumbaEngine interface {
+		NewPumbaWorkerFromSpec(spec pumba.WorkerSpec) pumba.Worker
+	}
+
+	// Document struct define suite document
+	//
+	//	version: resilia/v1
+	//	name: redis-pause
+	//	duration: 30m
+	//	manifests:
+	//	  - path: deployments/redis.yaml
+	//	  - inline: |
+	//	      apiVersion: v1
+	//	      kind: ConfigMap
+	//	      ...
+	//	workers:
+	//	  - target:
+	//	      deployment: redis-deployment
+	//	    interval: 20s
+	//	    pause:
+	//	      duration: 10s
+	//	wait:
+	//	  ready_timeout: 5m
+	//	teardown:
+	//	  rollback: true
+	Document struct {
+		Version   string             `yaml:"version"`
+		Name      string             `yaml:"name"`
+		Duration  string             `yaml:"duration"` // run stopped automatically once passed, default run until stopped
+		Manifests []Manifest         `yaml:"manifests"`
+		Workers   []pumba.WorkerSpec `yaml:"workers"`
+		Wait      Wait               `yaml:"wait"`
+		Teardown  Teardown           `yaml:"teardown"`
+	}
+
+	// Manifest struct define k8s manifest of suite, set only one of
+	// path or inline, inline can be yaml string or yaml object
+	Manifest struct {
+		Path   string    `yaml:"path"`   // manifest file or directory, relative to document
+		Inline yaml.Node `yaml:"inline"` // manifest content, may hold multiple yaml documents
+	}
+
+	// Wait struct define how long suite wait before chaos injected
+	Wait struct {
+		// ReadyTimeout max duration to wait applied workloads ready (default 5m)
+		ReadyTimeout string `yaml:"ready_timeout"`
+	}
+
+	// Teardown struct define suite teardown policy
+	Teardown struct {
+		// Rollback remove created resources when run failed (default true)
+		Rollback *bool `yaml:"rollback"`
+	}
+
+	// Engine struct act as function receiver and hold suite document
+	// parser requirement
+	Engine struct {
+		loader      ResourceLoader
+		pumbaEngine PumbaEngine
+	}
+)
+
+// New function return new suite document parser
+func New(loader ResourceLoader, pumbaEngine PumbaEngine) *Engine {
+	return &Engine{
+		loader:      loader,
+		pumbaEngine: pumbaEngine,
+	}
+}
+
+// ParseFile function read suite document from disk and turn it into suite model
+// manifest path resolved relative to the document's directory
+func (e *Engine) ParseFile(path string) (*services.Model, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.parse(data, filepath.Dir(path), true)
+}
+
+// Parse function turn suite document into suite model, manifest
+// referenced by path is rejected since there is no directory to resolve it
+// used by http api where document come from request body
+func (e *Engine) Parse(data []byte) (*services.Model, error) {
+	return e.parse(data, "", false)
+}
+
+func (e *Engine) parse(data []byte, dir string, allowPath bool) (*services.Model, error) {
+	var (
+		root yaml.Node
+		doc  Document
+		perr = &ParseError{}
+	)
+
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, perr.fromYAML(err)
+	}
+
+	if len(root.Content) == 0 {
+		perr.add(0, "", "document is empty")
+		return nil, perr
+	}
+
+	// decode strictly, so typo on field name reported instead of ignored
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, perr.fromYAML(err)
+	}
+
+	lines := nodeLines{root: root.Content[0]}
+
+	switch doc.Version {
+	case "":
+		perr.add(lines.of("version"), "version", "is required")
+	case Version:
+	default:
+		perr.add(lines.of("version"), "version", "unsupported version %q, expected %q", doc.Version, Version)
+	}
+
+	if doc.Name == "" {
+		perr.add(lines.of("name"), "name", "is required")
+	}
+
+	if doc.Duration != "" {
+		if duration, err := time.ParseDuration(doc.Duration); err != nil || duration <= 0 {
+			perr.add(lines.of("duration"), "duration", "invalid duration %q, must be positive", doc.Duration)
+		}
+	}
+
+	if doc.Wait.ReadyTimeout != "" {
+		if _, err := time.ParseDuration(doc.Wait.ReadyTimeout); err != nil {
+			perr.add(lines.of("wait.ready_timeout"), "wait.ready_timeout", "invalid duration %q", doc.Wait.ReadyTimeout)
+		}
+	}
+
+	for i, spec := range doc.Workers {
+		e.validateWorker(perr, lines, i, spec)
+	}
+
+	resources := e.loadManifests(perr, lines, doc.Manifests, dir, allowPath)
+
+	if len(perr.Errors) > 0 {
+		return nil, perr
+	}
+
+	return &services.Model{
+		ID:              uuid.New().String(),
+		Name:            doc.Name,
+		Resources:       resources,
+		PumbaWorkers:    doc.Workers,
+		ReadyTimeout:    doc.Wait.ReadyTimeout,
+		Duration:        doc.Duration,
+		DisableRollback: doc.Teardown.Rollback != nil && !*doc.Teardown.Rollback,
+	}, nil
+}
+
+// validateWorker function add every invalid field of worker to parse error
+func (e *Engine) validateWorker(perr *ParseError, lines nodeLines, index int, spec pumba.WorkerSpec) {
+	var (
+		prefix = fmt.Sprintf("workers[%d]", index)
+		worker = e.pumbaEngine.NewPumbaWorkerFromSpec(spec)
+	)
+
+	err := worker.Validate()
+	if err == nil {
+		return
+	}
+
+	var verr *pumba.ValidationError
+	if !errors.As(err, &verr) {
+		perr.add(lines.of(prefix), prefix, "%s", err)
+		return
+	}
+
+	for _, f := range verr.Errors {
+		field := prefix + "." + f.Field
+		perr.add(lines.of(field), field, "%s", f.Message)
+	}
+}
+
+// loadManifests function turn document manifests into suite file resources
+func (e *Engine) loadManifests(perr *ParseError, lines nodeLines, manifests []Manifest, dir string, allowPath bool) []services.FileResource {
+	var resources []services.FileResource
+
+	for i, manifest := range manifests {
+		var (
+			field     = fmt.Sprintf("manifests[%d]", i)
+			hasInline = manifest.Inline.Kind != 0
+			loaded    []services.FileResource
+			err       error
+		)
+
+		switch {
+		case manifest.Path != "" && hasInline:
+			perr.add(lines.of(field), field, "set only one of path or inline")
+			continue
+		case manifest.Path != "":
+			if !allowPath {
+				perr.add(lines.of(field+".path"), field+".path", "path is not allowed here, use inline manifest")
+				continue
+			}
+
+			path := manifest.Path
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+
+			loaded, err = e.loader.LoadFileResources(path)
+			field += ".path"
+		case hasInline:
+			var content []byte
+			content, err = inlineContent(manifest.Inline)
+			if err == nil {
+				loaded, err = e.loader.LoadFileResourcesFromBytes(content)
+			}
+			field += ".inline"
+		default:
+			perr.add(lines.of(field), field, "one of path or inline is required")
+			continue
+		}
+
+		if err != nil {
+			perr.add(lines.of(field), field, "%s", err)
+			continue
+		}
+
+		resources = append(resources, loaded...)
+	}
+
+	return resources
+}
+
+// inlineContent function return manifest content of inline node
+// yaml string used as is, yaml object marshaled back into yaml
+func inlineContent(node yaml.Node) ([]byte, error) {
+	if node.Kind == yaml.ScalarNode {
+		return []byte(node.Value), nil
+	}
+	return yaml.Marshal(&node)
+}
@@ -0,0 +1,349 @@
+package suitefile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/faruqisan/resilia/engine/suites/services"
+	"github.com/faruqisan/resilia/pkg/pumba"
+)
+
+// fakeLoader struct is resource loader turning each manifest document into
+// file resource holding it, document without kind rejected
+type fakeLoader struct {
+	paths []string
+}
+
+func (l *fakeLoader) LoadFileResources(path string) ([]services.FileResource, error) {
+	l.paths = append(l.paths, path)
+	return []services.FileResource{{Name: filepath.Base(path), Kind: services.KindManifest}}, nil
+}
+
+func (l *fakeLoader) LoadFileResourcesFromBytes(file []byte) ([]services.FileResource, error) {
+	var resources []services.FileResource
+	for _, doc := range strings.Split(string(file), "\n---\n") {
+		if !strings.Contains(doc, "kind:") {
+			return nil, errors.New("manifest must have apiVersion and kind")
+		}
+		resources = append(resources, services.FileResource{Kind: services.KindManifest, Value: strings.TrimSpace(doc)})
+	}
+	return resources, nil
+}
+
+// lines function join given lines into document, so line number of
+// each document line is it's index plus one
+func lines(l ...string) string {
+	return strings.Join(l, "\n") + "\n"
+}
+
+func TestParse(t *testing.T) {
+	e := New(&fakeLoader{}, pumba.New(nil))
+
+	model, err := e.Parse([]byte(lines(
+		"version: resilia/v1",
+		"name: redis-pause",
+		"duration: 30m",
+		"manifests:",
+		"  - inline: |",
+		"      apiVersion: v1",
+		"      kind: ConfigMap",
+		"      metadata:",
+		"        name: redis-config",
+		"  - inline:",
+		"      apiVersion: v1",
+		"      kind: Secret",
+		"      metadata:",
+		"        name: redis-secret",
+		"workers:",
+		"  - target:",
+		"      deployment: redis",
+		"    interval: 20s",
+		"    pause:",
+		"      duration: 10s",
+		"wait:",
+		"  ready_timeout: 2m",
+		"teardown:",
+		"  rollback: false",
+	)))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if model.ID == "" {
+		t.Error("model id is empty")
+	}
+
+	want := services.Model{
+		ID:   model.ID,
+		Name: "redis-pause",
+		Resources: []services.FileResource{
+			{Kind: services.KindManifest, Value: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: redis-config"},
+			// yaml object marshaled back with yaml's own indentation
+			{Kind: services.KindManifest, Value: "apiVersion: v1\nkind: Secret\nmetadata:\n    name: redis-secret"},
+		},
+		PumbaWorkers: []pumba.WorkerSpec{{
+			Target:   pumba.Target{Deployment: "redis"},
+			Interval: "20s",
+			Pause:    &pumba.PauseOptions{Duration: "10s"},
+		}},
+		DisableRollback: true,
+		ReadyTimeout:    "2m",
+		Duration:        "30m",
+	}
+
+	if !reflect.DeepEqual(*model, want) {
+		t.Errorf("Parse()\n got = %+v\nwant = %+v", *model, want)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	e := New(&fakeLoader{}, pumba.New(nil))
+
+	model, err := e.Parse([]byte(`{"version":"resilia/v1","name":"redis-pause","workers":[{"target":{"pod":"redis-0"},"interval":"1m","kill":{}}]}`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if model.Name != "redis-pause" || len(model.PumbaWorkers) != 1 || model.DisableRollback {
+		t.Errorf("Parse() = %+v", *model)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		// want errors, message matched by it's part
+		want []Error
+	}{
+		{
+			name: "empty",
+			doc:  "# nothing here\n",
+			want: []Error{{Message: "document is empty"}},
+		},
+		{
+			name: "unknown field",
+			doc:  lines("version: resilia/v1", "name: redis", "duraton: 30m"),
+			want: []Error{{Line: 3, Message: "field duraton not found"}},
+		},
+		{
+			name: "unknown worker field",
+			doc: lines(
+				"version: resilia/v1",
+				"name: redis",
+				"workers:",
+				"  - target:",
+				"      pod: redis-0",
+				"    interval: 20s",
+				"    pasue:",
+				"      duration: 10s",
+			),
+			want: []Error{{Line: 7, Message: "field pasue not found"}},
+		},
+		{
+			name: "wrong type",
+			doc:  lines("version: resilia/v1", "name: redis", "duration: [30m]"),
+			want: []Error{{Line: 3, Message: "cannot unmarshal !!seq into string"}},
+		},
+		{
+			name: "wrong type on list",
+			doc:  lines("version: resilia/v1", "name: redis", "workers:", "  target: redis"),
+			want: []Error{{Line: 4, Message: "cannot unmarshal !!map into []pumba.WorkerSpec"}},
+		},
+		{
+			name: "missing version and name",
+			doc:  lines("duration: 30m"),
+			want: []Error{
+				{Line: 1, Field: "version", Message: "is required"},
+				{Line: 1, Field: "name", Message: "is required"},
+			},
+		},
+		{
+			name: "unsupported version",
+			doc:  lines("name: redis", "version: resilia/v2"),
+			want: []Error{{Line: 2, Field: "version", Message: `unsupported version "resilia/v2"`}},
+		},
+		{
+			name: "invalid durations",
+			doc:  lines("version: resilia/v1", "name: redis", "duration: -5m", "wait:", "  ready_timeout: soon"),
+			want: []Error{
+				{Line: 3, Field: "duration", Message: "must be positive"},
+				{Line: 5, Field: "wait.ready_timeout", Message: `invalid duration "soon"`},
+			},
+		},
+		{
+			name: "invalid worker",
+			doc: lines(
+				"version: resilia/v1",
+				"name: redis",
+				"workers:",
+				"  - target:",
+				"      pod: redis-0",
+				"    interval: 20s",
+				"  - target:",
+				"      pod: redis-0",
+				"    kill:",
+				"      signal: TERM",
+			),
+			want: []Error{
+				// missing field pointed at it's closest parent
+				{Line: 4, Field: "workers[0].mode", Message: "is required"},
+				{Line: 7, Field: "workers[1].interval", Message: "is required"},
+				{Line: 10, Field: "workers[1].kill.signal", Message: `invalid signal "TERM"`},
+			},
+		},
+		{
+			name: "path not allowed",
+			doc:  lines("version: resilia/v1", "name: redis", "manifests:", "  - path: redis.yaml"),
+			want: []Error{{Line: 4, Field: "manifests[0].path", Message: "path is not allowed here"}},
+		},
+		{
+			name: "path and inline",
+			doc:  lines("version: resilia/v1", "name: redis", "manifests:", "  - path: redis.yaml", "    inline: 'kind: Service'"),
+			want: []Error{{Line: 4, Field: "manifests[0]", Message: "set only one of path or inline"}},
+		},
+		{
+			name: "neither path nor inline",
+			doc:  lines("version: resilia/v1", "name: redis", "manifests:", "  - inline: 'kind: Service'", "  - {}"),
+			want: []Error{{Line: 5, Field: "manifests[1]", Message: "one of path or inline is required"}},
+		},
+		{
+			name: "invalid inline manifest",
+			doc: lines(
+				"version: resilia/v1",
+				"name: redis",
+				"manifests:",
+				"  - inline: |",
+				"      apiVersion: v1",
+				"      metadata:",
+				"        name: redis-config",
+			),
+			want: []Error{{Line: 4, Field: "manifests[0].inline", Message: "must have apiVersion and kind"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(&fakeLoader{}, pumba.New(nil))
+
+			model, err := e.Parse([]byte(tt.doc))
+			if err == nil {
+				t.Fatalf("Parse() = %+v, want error", *model)
+			}
+
+			if !errors.Is(err, services.ErrInvalidSuite) {
+				t.Errorf("Parse() error = %v, want %v", err, services.ErrInvalidSuite)
+			}
+
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse() error = %#v, want *ParseError", err)
+			}
+
+			if len(perr.Errors) != len(tt.want) {
+				t.Fatalf("Parse() errors = %q, want %d errors", perr.Errors, len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				got := perr.Errors[i]
+				if got.Line != want.Line || got.Field != want.Field || !strings.Contains(got.Message, want.Message) {
+					t.Errorf("error[%d] = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resilia-suitefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		path     = filepath.Join(dir, "suite.yaml")
+		absolute = filepath.Join(dir, "shared", "redis.yaml")
+		loader   = &fakeLoader{}
+	)
+
+	doc := lines(
+		"version: resilia/v1",
+		"name: redis",
+		"manifests:",
+		"  - path: deployments",
+		"  - path: "+absolute,
+	)
+	if err := ioutil.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	model, err := New(loader, pumba.New(nil)).ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	// relative path resolved from document's directory
+	want := []string{filepath.Join(dir, "deployments"), absolute}
+	if !reflect.DeepEqual(loader.paths, want) {
+		t.Errorf("loaded paths = %q, want %q", loader.paths, want)
+	}
+
+	if len(model.Resources) != 2 {
+		t.Errorf("resources = %+v, want one per path", model.Resources)
+	}
+}
+
+func TestInlineFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resilia-suitefile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"suite.yaml":                lines("version: resilia/v1", "name: redis", "manifests:", "  - path: manifests", "  - path: manifests/service.yaml"),
+		"manifests/deployment.yaml": "kind: Deployment\n",
+		"manifests/service.yaml":    "kind: Service\n",
+		"manifests/notes.txt":       "not a manifest\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inlined, err := InlineFile(filepath.Join(dir, "suite.yaml"))
+	if err != nil {
+		t.Fatalf("InlineFile() error = %v", err)
+	}
+
+	// inlined document parsed where path not allowed
+	model, err := New(&fakeLoader{}, pumba.New(nil)).Parse(inlined)
+	if err != nil {
+		t.Fatalf("Parse() error = %v, document:\n%s", err, inlined)
+	}
+
+	var got []string
+	for _, resource := range model.Resources {
+		got = append(got, resource.Value)
+	}
+
+	want := []string{"kind: Deployment", "kind: Service", "kind: Service"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resources = %q, want %q", got, want)
+	}
+
+	if _, err := InlineFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("InlineFile() of missing document want error")
+	}
+}
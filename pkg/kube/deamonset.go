@@ -1,10 +1,15 @@
 package kube
 
 import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/scheme"
-	appsv1 "k8s.io/api/apps/v1"
 )
 
 // LoadDaemonSetFromFile function receive readed file in forms of byte
@@ -23,9 +28,12 @@ func (e *Engine) LoadDaemonSetFromFile(file []byte) (*appsv1.DaemonSet, error) {
 		return daemonSet, err
 	}
 
-	daemonSet = obj.(*appsv1.DaemonSet)
+	daemonSet, ok := obj.(*appsv1.DaemonSet)
+	if !ok {
+		return daemonSet, fmt.Errorf("expected DaemonSet object, got %s", obj.GetObjectKind().GroupVersionKind().Kind)
+	}
 
-	return daemonSet, err
+	return daemonSet, nil
 }
 
 // IsDaemonSetExist function check wheter daemon set exist on cluster
@@ -58,6 +66,21 @@ func (e *Engine) GetDaemonSets() ([]string, error) {
 	return daemonSets, err
 }
 
+// GetDaemonSet function return daemon set with given name
+func (e *Engine) GetDaemonSet(name string) (*appsv1.DaemonSet, error) {
+	return e.daemonSetsClient.Get(name, metav1.GetOptions{})
+}
+
+// ListDaemonSets function return daemon sets matching given label selector
+func (e *Engine) ListDaemonSets(labelSelector string) ([]appsv1.DaemonSet, error) {
+	ls, err := e.daemonSetsClient.List(metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	return ls.Items, nil
+}
+
 // CreateDaemonSet function will create a new daemon set on cluster
 // returning created daemon set info (name)
 func (e *Engine) CreateDaemonSet(daemonSet *appsv1.DaemonSet) (string, error) {
@@ -69,7 +92,54 @@ func (e *Engine) CreateDaemonSet(daemonSet *appsv1.DaemonSet) (string, error) {
 	return result.GetObjectMeta().GetName(), nil
 }
 
+// UpdateDaemonSet function will replace daemon set on cluster with given one
+// it's pods replaced according to the daemon set update strategy
+func (e *Engine) UpdateDaemonSet(daemonSet *appsv1.DaemonSet) error {
+	_, err := e.daemonSetsClient.Update(daemonSet)
+	return err
+}
+
 // DeleteDaemonSet function will remove daemon set from cluster
 func (e *Engine) DeleteDaemonSet(name string) error {
 	return e.daemonSetsClient.Delete(name, &metav1.DeleteOptions{})
 }
+
+// DeleteDaemonSetWithPods function will remove daemon set from cluster
+// using foreground propagation, so the daemon set kept on cluster
+// until all of it's pods terminated
+func (e *Engine) DeleteDaemonSetWithPods(name string) error {
+	propagation := metav1.DeletePropagationForeground
+	return e.daemonSetsClient.Delete(name, &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+}
+
+// WaitDaemonSetReady function block until daemon set rollout complete
+// and it's pods available on every scheduled node, or timeout reached
+func (e *Engine) WaitDaemonSetReady(name string, timeout time.Duration) error {
+
+	get := func() (runtime.Object, error) {
+		return e.daemonSetsClient.Get(name, metav1.GetOptions{})
+	}
+
+	watchFn := func(opts metav1.ListOptions) (watch.Interface, error) {
+		return e.daemonSetsClient.Watch(opts)
+	}
+
+	return waitForRollout("daemonset", name, timeout, get, watchFn, isDaemonSetReady)
+}
+
+func isDaemonSetReady(obj runtime.Object) (bool, error) {
+	daemonSet, ok := obj.(*appsv1.DaemonSet)
+	if !ok {
+		return false, fmt.Errorf("expected DaemonSet object, got %T", obj)
+	}
+
+	if daemonSet.Status.ObservedGeneration < daemonSet.Generation {
+		return false, nil
+	}
+
+	status := daemonSet.Status
+	return status.UpdatedNumberScheduled >= status.DesiredNumberScheduled &&
+		status.NumberAvailable >= status.DesiredNumberScheduled, nil
+}
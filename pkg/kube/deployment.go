@@ -1,9 +1,14 @@
 package kube
 
 import (
+	"fmt"
+	"time"
+
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
@@ -23,9 +28,12 @@ func (e *Engine) LoadDeploymentFromFile(file []byte) (*appsv1.Deployment, error)
 		return deployment, err
 	}
 
-	deployment = obj.(*appsv1.Deployment)
+	deployment, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return deployment, fmt.Errorf("expected Deployment object, got %s", obj.GetObjectKind().GroupVersionKind().Kind)
+	}
 
-	return deployment, err
+	return deployment, nil
 }
 
 // GetDeployments function will return list of existing deployments
@@ -58,6 +66,22 @@ func (e *Engine) IsDeploymentExist(name string) (bool, error) {
 	return !errors.IsNotFound(err), nil
 }
 
+// GetDeploymentSelector function return label selector of deployment
+// with given name on given namespace, used to find it's pods
+func (e *Engine) GetDeploymentSelector(namespace, name string) (string, error) {
+	deployment, err := e.clientSet.AppsV1().Deployments(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return "", err
+	}
+
+	return selector.String(), nil
+}
+
 // CreateDeployment function will create a new deployment on cluster
 // returning created deployment info (name)
 func (e *Engine) CreateDeployment(deployment *appsv1.Deployment) (string, error) {
@@ -73,3 +97,45 @@ func (e *Engine) CreateDeployment(deployment *appsv1.Deployment) (string, error)
 func (e *Engine) DeleteDeployment(name string) error {
 	return e.deploymentsClient.Delete(name, &metav1.DeleteOptions{})
 }
+
+// WaitDeploymentReady function block until deployment rollout complete
+// and all of it's updated replicas available, or timeout reached
+func (e *Engine) WaitDeploymentReady(name string, timeout time.Duration) error {
+
+	get := func() (runtime.Object, error) {
+		return e.deploymentsClient.Get(name, metav1.GetOptions{})
+	}
+
+	watchFn := func(opts metav1.ListOptions) (watch.Interface, error) {
+		return e.deploymentsClient.Watch(opts)
+	}
+
+	return waitForRollout("deployment", name, timeout, get, watchFn, isDeploymentReady)
+}
+
+func isDeploymentReady(obj runtime.Object) (bool, error) {
+	deployment, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return false, fmt.Errorf("expected Deployment object, got %T", obj)
+	}
+
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, nil
+	}
+
+	for _, condition := range deployment.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, fmt.Errorf("deployment %s exceeded it's progress deadline", deployment.Name)
+		}
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+	return status.UpdatedReplicas >= replicas &&
+		status.Replicas == status.UpdatedReplicas &&
+		status.AvailableReplicas >= status.UpdatedReplicas, nil
+}
@@ -5,10 +5,14 @@ import (
 	"path"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	appstypev1 "k8s.io/client-go/kubernetes/typed/apps/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -18,6 +22,13 @@ func int32Ptr(i int32) *int32 { return &i }
 // Option type used to customize kube Engine
 type Option func(*Engine)
 
+// resettableRESTMapper interface define REST mapper whose cache can be dropped
+// implemented by deferred discovery REST mapper
+type resettableRESTMapper interface {
+	meta.RESTMapper
+	Reset()
+}
+
 type (
 	// Engine struct wrap k8s client
 	// and act as function receiver
@@ -29,6 +40,8 @@ type (
 		deploymentsClient appstypev1.DeploymentInterface
 		servicesClient    corev1.ServiceInterface
 		daemonSetsClient  appstypev1.DaemonSetInterface
+		dynamicClient     dynamic.Interface
+		restMapper        resettableRESTMapper
 	}
 )
 
@@ -96,9 +109,21 @@ func New(options ...Option) (*Engine, error) {
 	e.servicesClient = servicesClient
 	e.daemonSetsClient = daemonSetsClient
 
+	dynamicClient, err := dynamic.NewForConfig(e.config)
+	if err != nil {
+		return e, err
+	}
+	e.dynamicClient = dynamicClient
+	e.restMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(e.clientSet.Discovery()))
+
 	return e, nil
 }
 
+// Namespace function return namespace used by engine
+func (e *Engine) Namespace() string {
+	return e.namespace
+}
+
 func homeDir() string {
 	if h := os.Getenv("HOME"); h != "" {
 		return h
@@ -0,0 +1,76 @@
+package kube
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// ListObjects function return objects of every listable kind that match
+// given label selector, namespaced objects only listed on engine namespace
+// object owned by another object skipped since it's removed along with it's owner
+func (e *Engine) ListObjects(labelSelector string) ([]unstructured.Unstructured, error) {
+
+	var objs []unstructured.Unstructured
+
+	// discovery may fail for some api groups (eg: unavailable aggregated api)
+	// but still return every resource it could discover
+	lists, err := discovery.ServerPreferredResources(e.clientSet.Discovery())
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return objs, err
+	}
+
+	lists = discovery.FilteredBy(discovery.SupportsAllVerbs{Verbs: []string{"list", "delete"}}, lists)
+
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			return objs, err
+		}
+
+		for _, resource := range list.APIResources {
+			// skip sub resource, eg: deployments/scale
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+
+			client := e.dynamicClient.Resource(gv.WithResource(resource.Name))
+
+			var result *unstructured.UnstructuredList
+			if resource.Namespaced {
+				result, err = client.Namespace(e.namespace).List(metav1.ListOptions{LabelSelector: labelSelector})
+			} else {
+				result, err = client.List(metav1.ListOptions{LabelSelector: labelSelector})
+			}
+			// kind engine not allowed to list skipped, so single
+			// forbidden kind never stop other kinds from being listed
+			if isUnlistable(err) {
+				continue
+			}
+			if err != nil {
+				return objs, err
+			}
+
+			for _, obj := range result.Items {
+				if len(obj.GetOwnerReferences()) > 0 {
+					continue
+				}
+
+				obj.SetGroupVersionKind(gv.WithKind(resource.Kind))
+				objs = append(objs, obj)
+			}
+		}
+	}
+
+	return objs, nil
+}
+
+// isUnlistable function return true when list failed because the kind
+// can't be listed by engine, eg: forbidden by rbac or removed meanwhile
+func isUnlistable(err error) bool {
+	return errors.IsForbidden(err) || errors.IsNotFound(err) || errors.IsMethodNotSupported(err)
+}
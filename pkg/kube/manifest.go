@@ -0,0 +1,286 @@
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ObjectRef struct identify k8s object of any kind by it's
+// group version kind and name
+type ObjectRef struct {
+	GVK  schema.GroupVersionKind
+	Name string
+}
+
+// String function return object ref in forms of group/version/kind/name
+// group is empty for core objects, eg: /v1/ConfigMap/redis-config
+func (r ObjectRef) String() string {
+	return strings.Join([]string{r.GVK.Group, r.GVK.Version, r.GVK.Kind, r.Name}, "/")
+}
+
+// ParseObjectRef function parse object ref from it's string forms
+func ParseObjectRef(ref string) (ObjectRef, error) {
+	parts := strings.Split(ref, "/")
+	if len(parts) != 4 || parts[1] == "" || parts[2] == "" || parts[3] == "" {
+		return ObjectRef{}, fmt.Errorf("invalid object ref %q, expected group/version/kind/name", ref)
+	}
+
+	return ObjectRef{
+		GVK: schema.GroupVersionKind{
+			Group:   parts[0],
+			Version: parts[1],
+			Kind:    parts[2],
+		},
+		Name: parts[3],
+	}, nil
+}
+
+// LoadObjectFromFile function receive readed file (yaml or json)
+// in forms of byte and return k8s object of any kind
+func (e *Engine) LoadObjectFromFile(file []byte) (*unstructured.Unstructured, error) {
+	return decodeObject(file)
+}
+
+func decodeObject(file []byte) (*unstructured.Unstructured, error) {
+
+	var raw map[string]interface{}
+
+	err := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(file), len(file)).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{Object: raw}
+
+	if obj.GetKind() == "" || obj.GetAPIVersion() == "" {
+		return nil, fmt.Errorf("object must have apiVersion and kind")
+	}
+
+	if obj.GetName() == "" {
+		return nil, fmt.Errorf("%s object must have metadata.name", obj.GetKind())
+	}
+
+	return obj, nil
+}
+
+// ManifestExtensions define file extensions of manifest files inside directory
+var ManifestExtensions = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+	".json": true,
+}
+
+// ManifestFiles function return given path when it's a file, or path of every
+// manifest file inside given directory (and it's sub directories) in lexical order
+func ManifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && ManifestExtensions[strings.ToLower(filepath.Ext(p))] {
+			files = append(files, p)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// LoadObjectsFromFile function receive readed file that may hold many
+// yaml documents separated by `---` and return each document as k8s object
+// of it's real kind, eg : *appsv1.Deployment, kind that unknown to client
+// scheme (eg : CRD) returned as *unstructured.Unstructured
+// document of list kind (eg : List, DeploymentList) expanded into it's items
+func (e *Engine) LoadObjectsFromFile(file []byte) ([]runtime.Object, error) {
+	return DecodeObjects(file)
+}
+
+// DecodeObjects function is LoadObjectsFromFile that doesn't need kube engine
+// nor cluster access, eg : to validate manifests offline
+func DecodeObjects(file []byte) ([]runtime.Object, error) {
+
+	var (
+		objs   []runtime.Object
+		reader = yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(file)))
+	)
+
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return objs, nil
+		}
+		if err != nil {
+			return objs, err
+		}
+
+		jsonDoc, err := yaml.ToJSON(doc)
+		if err != nil {
+			return objs, fmt.Errorf("document %d: %w", i, err)
+		}
+
+		// skip empty or comment only document
+		if trimmed := bytes.TrimSpace(jsonDoc); len(trimmed) == 0 || string(trimmed) == "null" {
+			continue
+		}
+
+		docObjs, err := decodeObjects(jsonDoc)
+		if err != nil {
+			return objs, fmt.Errorf("document %d: %w", i, err)
+		}
+
+		objs = append(objs, docObjs...)
+	}
+}
+
+// decodeObjects function decode single json document into k8s object
+// or into items of it when document is a list, each item must have
+// it's own apiVersion and kind
+func decodeObjects(jsonDoc []byte) ([]runtime.Object, error) {
+
+	obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(jsonDoc, nil, nil)
+	if runtime.IsNotRegisteredError(err) {
+		obj, err = decodeObject(jsonDoc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !meta.IsListType(obj) {
+		return []runtime.Object{obj}, nil
+	}
+
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(jsonDoc, &list); err != nil {
+		return nil, err
+	}
+
+	var objs []runtime.Object
+	for i, item := range list.Items {
+		itemObjs, err := decodeObjects(item)
+		if err != nil {
+			return objs, fmt.Errorf("item %d: %w", i, err)
+		}
+		objs = append(objs, itemObjs...)
+	}
+
+	return objs, nil
+}
+
+// Apply function create given object on cluster with given labels, or patch it
+// when it already exists, returning object name and whether it's created.
+// existing object not owned by the caller, so it's patched without given labels
+// and caller must not remove it as created object
+func (e *Engine) Apply(obj *unstructured.Unstructured, labels map[string]string) (string, bool, error) {
+
+	client, err := e.resourceClient(obj.GroupVersionKind(), obj)
+	if err != nil {
+		return "", false, err
+	}
+
+	created := obj.DeepCopy()
+	objLabels := created.GetLabels()
+	if objLabels == nil {
+		objLabels = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		objLabels[k] = v
+	}
+	created.SetLabels(objLabels)
+
+	result, err := client.Create(created, metav1.CreateOptions{})
+	if err == nil {
+		return result.GetName(), true, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return "", false, err
+	}
+
+	// merge patch work on every kind, including CRD without strategic merge
+	patch, err := obj.MarshalJSON()
+	if err != nil {
+		return "", false, err
+	}
+
+	result, err = client.Patch(obj.GetName(), types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return "", false, err
+	}
+
+	return result.GetName(), false, nil
+}
+
+// Delete function will remove object with given kind and name from cluster
+func (e *Engine) Delete(gvk schema.GroupVersionKind, name string) error {
+
+	client, err := e.resourceClient(gvk, nil)
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	return client.Delete(name, &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+}
+
+// resourceClient function return dynamic client of given kind
+// namespaced kind scoped to engine namespace, and obj (if any)
+// namespace will be defaulted to it
+func (e *Engine) resourceClient(gvk schema.GroupVersionKind, obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+
+	mapping, err := e.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		// kind may be registered after mapper cached, eg: freshly applied CRD
+		e.restMapper.Reset()
+		mapping, err = e.restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return e.dynamicClient.Resource(mapping.Resource), nil
+	}
+
+	if obj != nil {
+		switch obj.GetNamespace() {
+		case "":
+			obj.SetNamespace(e.namespace)
+		case e.namespace:
+		default:
+			return nil, fmt.Errorf("%s %s namespace %q differs from engine namespace %q", gvk.Kind, obj.GetName(), obj.GetNamespace(), e.namespace)
+		}
+	}
+
+	return e.dynamicClient.Resource(mapping.Resource).Namespace(e.namespace), nil
+}
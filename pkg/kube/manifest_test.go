@@ -0,0 +1,446 @@
+package kube
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// fakeRESTMapper struct is REST mapper of preset kinds whose cache never dropped
+type fakeRESTMapper struct {
+	*meta.DefaultRESTMapper
+}
+
+func (m fakeRESTMapper) Reset() {}
+
+var (
+	configMapGVK   = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	clusterRoleGVK = schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}
+)
+
+// newTestEngine function return engine on fake cluster knowing config map
+// and cluster role, holding given objects
+func newTestEngine(objects ...runtime.Object) (*Engine, *dynamicfake.FakeDynamicClient) {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(configMapGVK, meta.RESTScopeNamespace)
+	mapper.Add(clusterRoleGVK, meta.RESTScopeRoot)
+
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+
+	return &Engine{
+		namespace:     "chaos",
+		dynamicClient: client,
+		restMapper:    fakeRESTMapper{mapper},
+	}, client
+}
+
+func newObject(gvk schema.GroupVersionKind, namespace, name string, labels map[string]string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func TestParseObjectRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want ObjectRef
+		err  bool
+	}{
+		{ref: "/v1/ConfigMap/redis-config", want: ObjectRef{GVK: configMapGVK, Name: "redis-config"}},
+		{ref: "rbac.authorization.k8s.io/v1/ClusterRole/reader", want: ObjectRef{GVK: clusterRoleGVK, Name: "reader"}},
+		{ref: "", err: true},
+		{ref: "v1/ConfigMap/redis-config", err: true},
+		{ref: "apps/v1/Deployment/redis/extra", err: true},
+		{ref: "apps//Deployment/redis", err: true},
+		{ref: "apps/v1//redis", err: true},
+		{ref: "apps/v1/Deployment/", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseObjectRef(tt.ref)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("ParseObjectRef() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseObjectRef() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseObjectRef() = %+v, want %+v", got, tt.want)
+			}
+
+			if got.String() != tt.ref {
+				t.Errorf("String() = %q, want %q", got.String(), tt.ref)
+			}
+		})
+	}
+}
+
+func TestLoadObjectFromFile(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		// err part of expected error message, empty when object valid
+		err string
+	}{
+		{
+			name: "yaml",
+			file: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: redis-config\n",
+		},
+		{
+			name: "json",
+			file: `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"redis-config"}}`,
+		},
+		{
+			name: "empty",
+			file: "",
+			err:  "EOF",
+		},
+		{
+			name: "malformed",
+			file: "apiVersion: v1\nkind: [ConfigMap\n",
+			err:  "yaml",
+		},
+		{
+			name: "not an object",
+			file: "- apiVersion: v1\n- kind: ConfigMap\n",
+			err:  "cannot unmarshal array",
+		},
+		{
+			name: "missing kind",
+			file: "apiVersion: v1\nmetadata:\n  name: redis-config\n",
+			err:  "must have apiVersion and kind",
+		},
+		{
+			name: "missing name",
+			file: "apiVersion: v1\nkind: ConfigMap\n",
+			err:  "must have metadata.name",
+		},
+		{
+			name: "metadata of wrong type",
+			file: "apiVersion: v1\nkind: ConfigMap\nmetadata: redis-config\n",
+			err:  "must have metadata.name",
+		},
+	}
+
+	e := new(Engine)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, err := e.LoadObjectFromFile([]byte(tt.file))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("LoadObjectFromFile() error = %v", err)
+				}
+				if obj.GroupVersionKind() != configMapGVK || obj.GetName() != "redis-config" {
+					t.Errorf("LoadObjectFromFile() = %s %s, want ConfigMap redis-config", obj.GroupVersionKind(), obj.GetName())
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("LoadObjectFromFile() error = %v, want containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestResourceClientNamespace(t *testing.T) {
+	tests := []struct {
+		name string
+		obj  *unstructured.Unstructured
+		// namespace of the object once client returned
+		namespace string
+		err       bool
+	}{
+		{
+			name:      "namespaced defaulted to engine namespace",
+			obj:       newObject(configMapGVK, "", "redis-config", nil),
+			namespace: "chaos",
+		},
+		{
+			name:      "namespaced on engine namespace",
+			obj:       newObject(configMapGVK, "chaos", "redis-config", nil),
+			namespace: "chaos",
+		},
+		{
+			name: "namespaced on other namespace",
+			obj:  newObject(configMapGVK, "kube-system", "redis-config", nil),
+			err:  true,
+		},
+		{
+			name: "cluster scoped left without namespace",
+			obj:  newObject(clusterRoleGVK, "", "reader", nil),
+		},
+		{
+			name: "unknown kind",
+			obj:  newObject(schema.GroupVersionKind{Group: "chaos.io", Version: "v1", Kind: "Experiment"}, "", "redis", nil),
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := newTestEngine()
+
+			_, err := e.resourceClient(tt.obj.GroupVersionKind(), tt.obj)
+			if tt.err {
+				if err == nil {
+					t.Fatal("resourceClient() want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resourceClient() error = %v", err)
+			}
+
+			if tt.obj.GetNamespace() != tt.namespace {
+				t.Errorf("namespace = %q, want %q", tt.obj.GetNamespace(), tt.namespace)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	var (
+		runLabels = map[string]string{"resilia.io/run-id": "run-1"}
+		existing  = newObject(configMapGVK, "chaos", "existing", map[string]string{"app": "redis"})
+	)
+	existing.Object["data"] = map[string]interface{}{"maxmemory": "1mb"}
+
+	tests := []struct {
+		name    string
+		obj     *unstructured.Unstructured
+		created bool
+		labels  map[string]string
+		data    map[string]interface{}
+	}{
+		{
+			name:    "created with given labels",
+			obj:     newObject(configMapGVK, "", "fresh", map[string]string{"app": "redis"}),
+			created: true,
+			labels:  map[string]string{"app": "redis", "resilia.io/run-id": "run-1"},
+		},
+		{
+			name:    "existing patched without given labels",
+			obj:     newObject(configMapGVK, "", "existing", nil),
+			created: false,
+			labels:  map[string]string{"app": "redis"},
+			data:    map[string]interface{}{"maxmemory": "2mb"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, client := newTestEngine(existing.DeepCopy())
+			if tt.data != nil {
+				tt.obj.Object["data"] = tt.data
+			}
+
+			name, created, err := e.Apply(tt.obj, runLabels)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			if name != tt.obj.GetName() || created != tt.created {
+				t.Errorf("Apply() = %s, %v, want %s, %v", name, created, tt.obj.GetName(), tt.created)
+			}
+
+			gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+			got, err := client.Resource(gvr).Namespace("chaos").Get(name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.GetLabels(), tt.labels) {
+				t.Errorf("labels = %v, want %v", got.GetLabels(), tt.labels)
+			}
+
+			if tt.data != nil && !reflect.DeepEqual(got.Object["data"], tt.data) {
+				t.Errorf("data = %v, want %v", got.Object["data"], tt.data)
+			}
+		})
+	}
+}
+
+func TestLoadObjectsFromFile(t *testing.T) {
+	const (
+		deployment = "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: redis\n"
+		service    = "apiVersion: v1\nkind: Service\nmetadata:\n  name: redis\n"
+		crd        = "apiVersion: chaos.io/v1\nkind: Experiment\nmetadata:\n  name: redis\n"
+	)
+
+	tests := []struct {
+		name string
+		file string
+		// want type and name of each loaded object
+		want []string
+		// err part of expected error message
+		err string
+	}{
+		{
+			name: "single document",
+			file: deployment,
+			want: []string{"*v1.Deployment redis"},
+		},
+		{
+			name: "multi documents",
+			file: deployment + "---\n" + service + "---\n" + crd,
+			want: []string{"*v1.Deployment redis", "*v1.Service redis", "*unstructured.Unstructured redis"},
+		},
+		{
+			name: "empty and comment only documents",
+			file: "---\n" + deployment + "---\n---\n# service moved to other file\n---\n" + service + "---\n",
+			want: []string{"*v1.Deployment redis", "*v1.Service redis"},
+		},
+		{
+			name: "empty file",
+			file: "",
+		},
+		{
+			name: "list",
+			file: `{"apiVersion":"v1","kind":"List","items":[` +
+				`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"redis"}},` +
+				`{"apiVersion":"v1","kind":"Service","metadata":{"name":"redis"}}]}`,
+			want: []string{"*v1.Deployment redis", "*v1.Service redis"},
+		},
+		{
+			name: "typed list beside other document",
+			file: "apiVersion: apps/v1\nkind: DeploymentList\nitems:\n" +
+				"- apiVersion: apps/v1\n  kind: Deployment\n  metadata:\n    name: redis\n" +
+				"- apiVersion: apps/v1\n  kind: Deployment\n  metadata:\n    name: sentinel\n" +
+				"---\n" + service,
+			want: []string{"*v1.Deployment redis", "*v1.Deployment sentinel", "*v1.Service redis"},
+		},
+		{
+			name: "empty list",
+			file: "apiVersion: v1\nkind: List\nitems: []\n",
+		},
+		{
+			name: "list item without kind",
+			file: "apiVersion: v1\nkind: List\nitems:\n- metadata:\n    name: redis\n",
+			err:  "document 0: item 0:",
+		},
+		{
+			name: "invalid later document",
+			file: deployment + "---\napiVersion: v1\nmetadata:\n  name: redis\n",
+			err:  "document 1:",
+		},
+		{
+			name: "malformed document",
+			file: deployment + "---\nkind: [Service\n",
+			err:  "document 1:",
+		},
+	}
+
+	e := new(Engine)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, err := e.LoadObjectsFromFile([]byte(tt.file))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("LoadObjectsFromFile() error = %v, want containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadObjectsFromFile() error = %v", err)
+			}
+
+			var got []string
+			for _, obj := range objs {
+				accessor, err := meta.Accessor(obj)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, fmt.Sprintf("%T %s", obj, accessor.GetName()))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadObjectsFromFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resilia-manifests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"redis.yaml", "README.md", "config/redis.JSON", "config/sentinel.yml", "config/.keep"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want []string
+		err  bool
+	}{
+		{
+			name: "directory",
+			path: dir,
+			want: []string{"config/redis.JSON", "config/sentinel.yml", "redis.yaml"},
+		},
+		{
+			name: "sub directory",
+			path: filepath.Join(dir, "config"),
+			want: []string{"config/redis.JSON", "config/sentinel.yml"},
+		},
+		{
+			name: "file loaded whatever it's extension",
+			path: filepath.Join(dir, "README.md"),
+			want: []string{"README.md"},
+		},
+		{
+			name: "missing",
+			path: filepath.Join(dir, "missing"),
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := ManifestFiles(tt.path)
+			if (err != nil) != tt.err {
+				t.Fatalf("ManifestFiles() error = %v, want error %v", err, tt.err)
+			}
+
+			var got []string
+			for _, file := range files {
+				rel, _ := filepath.Rel(dir, file)
+				got = append(got, filepath.ToSlash(rel))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ManifestFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
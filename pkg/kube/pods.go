@@ -1,7 +1,9 @@
 package kube
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 )
 
 // GetPods fuction return all pods name
@@ -22,3 +24,26 @@ func (e *Engine) GetPods() ([]string, error) {
 
 	return podNames, err
 }
+
+// GetRunningPods function return name of running pods on given namespace
+// that match label selector, empty selector match every pod
+func (e *Engine) GetRunningPods(namespace, labelSelector string) ([]string, error) {
+	var (
+		podNames []string
+		err      error
+	)
+
+	pods, err := e.clientSet.CoreV1().Pods(namespace).List(metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: fields.OneTermEqualSelector("status.phase", string(corev1.PodRunning)).String(),
+	})
+	if err != nil {
+		return podNames, err
+	}
+
+	for _, pod := range pods.Items {
+		podNames = append(podNames, pod.Name)
+	}
+
+	return podNames, err
+}
@@ -0,0 +1,96 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type (
+	// rolloutGetFunc get current state of watched object
+	rolloutGetFunc func() (runtime.Object, error)
+	// rolloutWatchFunc start watching object that match given options
+	rolloutWatchFunc func(opts metav1.ListOptions) (watch.Interface, error)
+	// rolloutReadyFunc return true when object rollout complete
+	// returning error will stop waiting, eg : rollout deadline exceeded
+	rolloutReadyFunc func(obj runtime.Object) (bool, error)
+)
+
+// waitForRollout function watch object with given name until ready
+// return true or timeout reached, watch re-established when closed by server
+func waitForRollout(kind, name string, timeout time.Duration, get rolloutGetFunc, watchFn rolloutWatchFunc, ready rolloutReadyFunc) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	fieldSelector := fields.OneTermEqualSelector("metadata.name", name).String()
+
+	for {
+		obj, err := get()
+		if err != nil {
+			return err
+		}
+
+		done, err := ready(obj)
+		if err != nil || done {
+			return err
+		}
+
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+
+		watcher, err := watchFn(metav1.ListOptions{
+			FieldSelector:   fieldSelector,
+			ResourceVersion: accessor.GetResourceVersion(),
+		})
+		if err != nil {
+			return err
+		}
+
+		done, err = watchUntilReady(ctx, watcher, ready)
+		watcher.Stop()
+		if err != nil || done {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return fmt.Errorf("timeout waiting %s %s to be ready after %s", kind, name, timeout)
+		}
+	}
+}
+
+// watchUntilReady function consume watch events until object ready
+// returning false without error when watch closed or context done
+func watchUntilReady(ctx context.Context, watcher watch.Interface, ready rolloutReadyFunc) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return false, nil
+			}
+
+			switch event.Type {
+			case watch.Deleted:
+				return false, fmt.Errorf("object deleted while waiting to be ready")
+			case watch.Error:
+				// mostly resource version too old, re-watch from latest state
+				return false, nil
+			}
+
+			done, err := ready(event.Object)
+			if err != nil || done {
+				return done, err
+			}
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package kube
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// readyCase struct define expected result of rollout ready function for given object
+type readyCase struct {
+	name  string
+	obj   runtime.Object
+	ready bool
+	err   bool
+}
+
+func testReady(t *testing.T, ready rolloutReadyFunc, tests []readyCase) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ready(tt.obj)
+			if (err != nil) != tt.err {
+				t.Fatalf("ready() error = %v, want error %v", err, tt.err)
+			}
+			if got != tt.ready {
+				t.Errorf("ready() = %v, want %v", got, tt.ready)
+			}
+		})
+	}
+}
+
+func newDeployment(replicas *int32, status appsv1.DeploymentStatus) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: replicas},
+		Status:     status,
+	}
+}
+
+func TestIsDeploymentReady(t *testing.T) {
+	deadlineExceeded := []appsv1.DeploymentCondition{{
+		Type:   appsv1.DeploymentProgressing,
+		Status: corev1.ConditionFalse,
+		Reason: "ProgressDeadlineExceeded",
+	}}
+
+	testReady(t, isDeploymentReady, []readyCase{
+		{
+			name:  "rolled out",
+			obj:   newDeployment(int32Ptr(3), appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}),
+			ready: true,
+		},
+		{
+			name:  "replicas default to one",
+			obj:   newDeployment(nil, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}),
+			ready: true,
+		},
+		{
+			name: "spec not observed yet",
+			obj:  newDeployment(int32Ptr(3), appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}),
+		},
+		{
+			name: "replicas not updated",
+			obj:  newDeployment(int32Ptr(3), appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 3}),
+		},
+		{
+			name: "old replicas still running",
+			obj:  newDeployment(int32Ptr(3), appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 4, UpdatedReplicas: 3, AvailableReplicas: 3}),
+		},
+		{
+			name: "updated replicas not available",
+			obj:  newDeployment(int32Ptr(3), appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 2}),
+		},
+		{
+			name: "progress deadline exceeded",
+			obj:  newDeployment(int32Ptr(3), appsv1.DeploymentStatus{ObservedGeneration: 2, Conditions: deadlineExceeded}),
+			err:  true,
+		},
+		{
+			name: "other kind",
+			obj:  &appsv1.StatefulSet{},
+			err:  true,
+		},
+	})
+}
+
+func newStatefulSet(replicas *int32, strategy appsv1.StatefulSetUpdateStrategyType, status appsv1.StatefulSetStatus) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis", Generation: 2},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:       replicas,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: strategy},
+		},
+		Status: status,
+	}
+}
+
+func TestIsStatefulSetReady(t *testing.T) {
+	const (
+		rolling  = appsv1.RollingUpdateStatefulSetStrategyType
+		onDelete = appsv1.OnDeleteStatefulSetStrategyType
+	)
+
+	testReady(t, isStatefulSetReady, []readyCase{
+		{
+			name:  "rolled out",
+			obj:   newStatefulSet(int32Ptr(3), rolling, appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, CurrentRevision: "r2", UpdateRevision: "r2"}),
+			ready: true,
+		},
+		{
+			name:  "replicas default to one",
+			obj:   newStatefulSet(nil, rolling, appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 1}),
+			ready: true,
+		},
+		{
+			name: "spec not observed yet",
+			obj:  newStatefulSet(int32Ptr(3), rolling, appsv1.StatefulSetStatus{ObservedGeneration: 1, ReadyReplicas: 3}),
+		},
+		{
+			name: "replicas not ready",
+			obj:  newStatefulSet(int32Ptr(3), rolling, appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 2}),
+		},
+		{
+			name: "rolling update in progress",
+			obj:  newStatefulSet(int32Ptr(3), rolling, appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, CurrentRevision: "r1", UpdateRevision: "r2"}),
+		},
+		{
+			name:  "on delete strategy ignore revision",
+			obj:   newStatefulSet(int32Ptr(3), onDelete, appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 3, CurrentRevision: "r1", UpdateRevision: "r2"}),
+			ready: true,
+		},
+		{
+			name: "other kind",
+			obj:  &appsv1.Deployment{},
+			err:  true,
+		},
+	})
+}
+
+func newDaemonSet(status appsv1.DaemonSetStatus) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-exporter", Generation: 2},
+		Status:     status,
+	}
+}
+
+func TestIsDaemonSetReady(t *testing.T) {
+	testReady(t, isDaemonSetReady, []readyCase{
+		{
+			name:  "rolled out",
+			obj:   newDaemonSet(appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}),
+			ready: true,
+		},
+		{
+			name:  "no node scheduled",
+			obj:   newDaemonSet(appsv1.DaemonSetStatus{ObservedGeneration: 2}),
+			ready: true,
+		},
+		{
+			name: "spec not observed yet",
+			obj:  newDaemonSet(appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3}),
+		},
+		{
+			name: "pods not updated",
+			obj:  newDaemonSet(appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 2, NumberAvailable: 3}),
+		},
+		{
+			name: "pods not available",
+			obj:  newDaemonSet(appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 2}),
+		},
+		{
+			name: "other kind",
+			obj:  &appsv1.Deployment{},
+			err:  true,
+		},
+	})
+}
+
+func TestWaitForRollout(t *testing.T) {
+	var (
+		progressing = newDeployment(int32Ptr(1), appsv1.DeploymentStatus{ObservedGeneration: 2})
+		rolledOut   = newDeployment(int32Ptr(1), appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1})
+	)
+
+	tests := []struct {
+		name    string
+		current runtime.Object
+		// events sent on watch, watch left open after it
+		events []watch.Event
+		// err part of expected error message, empty when rollout complete
+		err string
+	}{
+		{
+			name:    "ready before watch",
+			current: rolledOut,
+		},
+		{
+			name:    "ready on watch event",
+			current: progressing,
+			events:  []watch.Event{{Type: watch.Modified, Object: progressing}, {Type: watch.Modified, Object: rolledOut}},
+		},
+		{
+			name:    "deleted while waiting",
+			current: progressing,
+			events:  []watch.Event{{Type: watch.Deleted, Object: progressing}},
+			err:     "deleted",
+		},
+		{
+			name:    "never ready",
+			current: progressing,
+			events:  []watch.Event{{Type: watch.Modified, Object: progressing}},
+			err:     "timeout waiting deployment redis",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			get := func() (runtime.Object, error) {
+				return tt.current, nil
+			}
+
+			watchFn := func(opts metav1.ListOptions) (watch.Interface, error) {
+				if opts.FieldSelector != "metadata.name=redis" {
+					t.Errorf("field selector = %q, want metadata.name=redis", opts.FieldSelector)
+				}
+
+				watcher := watch.NewFakeWithChanSize(len(tt.events), false)
+				for _, event := range tt.events {
+					watcher.Action(event.Type, event.Object)
+				}
+				return watcher, nil
+			}
+
+			err := waitForRollout("deployment", "redis", 50*time.Millisecond, get, watchFn, isDeploymentReady)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("waitForRollout() error = %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("waitForRollout() error = %v, want containing %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package kube
 
 import (
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -23,9 +25,12 @@ func (e *Engine) LoadServiceFromFile(file []byte) (*corev1.Service, error) {
 		return s, err
 	}
 
-	s = obj.(*corev1.Service)
+	s, ok := obj.(*corev1.Service)
+	if !ok {
+		return s, fmt.Errorf("expected Service object, got %s", obj.GetObjectKind().GroupVersionKind().Kind)
+	}
 
-	return s, err
+	return s, nil
 }
 
 // IsServiceExist function check wheter service exist on cluster
@@ -72,4 +77,4 @@ func (e *Engine) CreateService(service *corev1.Service) (string, error) {
 // DeleteService function will remove service from cluster
 func (e *Engine) DeleteService(name string) error {
 	return e.servicesClient.Delete(name, &metav1.DeleteOptions{})
-}
\ No newline at end of file
+}
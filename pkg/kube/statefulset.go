@@ -0,0 +1,54 @@
+package kube
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// WaitStatefulSetReady function block until stateful set rollout complete
+// and all of it's replicas ready, or timeout reached
+func (e *Engine) WaitStatefulSetReady(name string, timeout time.Duration) error {
+	client := e.clientSet.AppsV1().StatefulSets(e.namespace)
+
+	get := func() (runtime.Object, error) {
+		return client.Get(name, metav1.GetOptions{})
+	}
+
+	watchFn := func(opts metav1.ListOptions) (watch.Interface, error) {
+		return client.Watch(opts)
+	}
+
+	return waitForRollout("statefulset", name, timeout, get, watchFn, isStatefulSetReady)
+}
+
+func isStatefulSetReady(obj runtime.Object) (bool, error) {
+	sts, ok := obj.(*appsv1.StatefulSet)
+	if !ok {
+		return false, fmt.Errorf("expected StatefulSet object, got %T", obj)
+	}
+
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return false, nil
+	}
+
+	replicas := int32(1)
+	if sts.Spec.Replicas != nil {
+		replicas = *sts.Spec.Replicas
+	}
+
+	if sts.Status.ReadyReplicas < replicas {
+		return false, nil
+	}
+
+	if sts.Spec.UpdateStrategy.Type == appsv1.RollingUpdateStatefulSetStrategyType &&
+		sts.Status.UpdateRevision != sts.Status.CurrentRevision {
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,131 @@
+package pumba
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// CommandIPTables is worker command to drop container traffic using iptables
+	CommandIPTables WorkerCommandMode = "iptables"
+
+	// IPTablesLossRandom drop packets randomly by probability
+	IPTablesLossRandom IPTablesLossMode = "random"
+	// IPTablesLossNth drop every nth packets
+	IPTablesLossNth IPTablesLossMode = "nth"
+)
+
+type (
+	// IPTablesLossMode type define how iptables worker drop packets
+	IPTablesLossMode string
+
+	// IPTablesOptions struct define iptables options
+	IPTablesOptions struct {
+		// Image docker image with iptables; try 'biarca/iptables'
+		Image string `json:"image" yaml:"image"`
+		// Duration emulation duration: must be shorter than recurrent interval;
+		// use with optional unit suffix: 'ms/s/m/h'
+		Duration string `json:"duration" yaml:"duration"`
+		// Interface network interface to apply filter on (default: "eth0")
+		Interface string `json:"interface" yaml:"interface"`
+		// Protocol protocol to filter, can be one of {any | tcp | udp | icmp} (default: any)
+		Protocol string `json:"protocol" yaml:"protocol"`
+		// Source source IP filter; supports multiple IPs separated by comma; supports CIDR notation
+		Source string `json:"source" yaml:"source"`
+		// Destination destination IP filter; supports multiple IPs separated by comma; supports CIDR notation
+		Destination string `json:"destination" yaml:"destination"`
+		// SrcPort source port filter; supports multiple ports separated by comma
+		SrcPort string `json:"src_port" yaml:"src_port"`
+		// DstPort destination port filter; supports multiple ports separated by comma
+		DstPort string `json:"dst_port" yaml:"dst_port"`
+		// PullImage try to pull iptables image
+		PullImage bool `json:"pull_image" yaml:"pull_image"`
+
+		// Mode packet loss mode (default: random)
+		Mode IPTablesLossMode `json:"mode" yaml:"mode"`
+		// Probability packet drop probability on random mode, between 0.0 and 1.0
+		Probability float64 `json:"probability" yaml:"probability"`
+		// Every drop every nth packet on nth mode
+		Every int `json:"every" yaml:"every"`
+		// Packet initial packet counter on nth mode, smaller than Every
+		Packet int `json:"packet" yaml:"packet"`
+	}
+)
+
+// IPTables function set options to worker to use iptables
+func (e *Engine) IPTables(options IPTablesOptions) WorkerOptions {
+	return func(w *Worker) {
+		w.ipTablesOptions = options
+		w.optionModes = append(w.optionModes, CommandIPTables)
+	}
+}
+
+// parseIPTablesArgs function return iptables args, iptables options
+// come first followed by loss command and it's own options
+func (w Worker) parseIPTablesArgs() []string {
+	var (
+		opts = w.ipTablesOptions
+		args []string
+	)
+
+	if opts.Duration != "" {
+		args = append(args, "--duration", opts.Duration)
+	}
+
+	if opts.Interface != "" {
+		args = append(args, "--interface", opts.Interface)
+	}
+
+	if opts.Protocol != "" {
+		args = append(args, "--protocol", opts.Protocol)
+	}
+
+	for _, source := range splitList(opts.Source) {
+		args = append(args, "--source", source)
+	}
+
+	for _, destination := range splitList(opts.Destination) {
+		args = append(args, "--destination", destination)
+	}
+
+	if opts.SrcPort != "" {
+		args = append(args, "--src-port", opts.SrcPort)
+	}
+
+	if opts.DstPort != "" {
+		args = append(args, "--dst-port", opts.DstPort)
+	}
+
+	if opts.Image != "" {
+		args = append(args, "--iptables-image", opts.Image)
+	}
+
+	if opts.PullImage {
+		args = append(args, "--pull-image")
+	}
+
+	args = append(args, "loss")
+
+	switch opts.Mode {
+	case IPTablesLossNth:
+		args = append(args, "--mode", string(IPTablesLossNth), "--every", strconv.Itoa(opts.Every))
+		if opts.Packet > 0 {
+			args = append(args, "--packet", strconv.Itoa(opts.Packet))
+		}
+	default:
+		args = append(args, "--mode", string(IPTablesLossRandom), "--probability", formatFloat(opts.Probability))
+	}
+
+	return args
+}
+
+// splitList function split comma separated list, ignoring empty item
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
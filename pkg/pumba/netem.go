@@ -0,0 +1,215 @@
+package pumba
+
+import (
+	"strconv"
+)
+
+type (
+	// DelayOptions struct define netem delay options
+	DelayOptions struct {
+		Time         int    `json:"time" yaml:"time"`                 // delay time in milliseconds
+		Jitter       int    `json:"jitter" yaml:"jitter"`             // random delay variation (jitter) in milliseconds, eg: 3000ms ± 40ms
+		Correlation  int    `json:"correlation" yaml:"correlation"`   // delay correlation in whole percentage, 0 to 100
+		Distribution string `json:"distribution" yaml:"distribution"` // delay distribution, can be one of {<empty> | uniform | normal | pareto | paretonormal}
+	}
+
+	// LossOptions struct define netem loss options
+	LossOptions struct {
+		Percent     float64 `json:"percent" yaml:"percent"`         // packet loss percentage
+		Correlation float64 `json:"correlation" yaml:"correlation"` // loss correlation in percentage
+	}
+
+	// LossStateOptions struct define netem loss-state options
+	// of 4-state Markov model, zero value use pumba default
+	LossStateOptions struct {
+		P13 float64 `json:"p13" yaml:"p13"` // probability to go from state (1) to state (3)
+		P31 float64 `json:"p31" yaml:"p31"` // probability to go from state (3) to state (1)
+		P32 float64 `json:"p32" yaml:"p32"` // probability to go from state (3) to state (2)
+		P23 float64 `json:"p23" yaml:"p23"` // probability to go from state (2) to state (3)
+		P14 float64 `json:"p14" yaml:"p14"` // probability to go from state (1) to state (4)
+	}
+
+	// LossGEModelOptions struct define netem loss-gemodel options
+	// of Gilbert-Elliot model, zero value use pumba default
+	LossGEModelOptions struct {
+		PG float64 `json:"pg" yaml:"pg"` // transition probability into the bad state
+		PB float64 `json:"pb" yaml:"pb"` // transition probability into the good state
+		H  float64 `json:"h" yaml:"h"`   // loss probability in the bad state
+		K  float64 `json:"k" yaml:"k"`   // loss probability in the good state
+	}
+
+	// RateOptions struct define netem rate options
+	RateOptions struct {
+		Rate           string `json:"rate" yaml:"rate"`                       // delay outgoing packets; common units: kbit, mbit, gbit
+		PacketOverhead int    `json:"packet_overhead" yaml:"packet_overhead"` // per packet overhead; in bytes
+		CellSize       int    `json:"cell_size" yaml:"cell_size"`             // cell size of the simulated link layer scheme
+		CellOverhead   int    `json:"cell_overhead" yaml:"cell_overhead"`     // per cell overhead; in bytes
+	}
+
+	// DuplicateOptions struct define netem duplicate options
+	DuplicateOptions struct {
+		Percent     float64 `json:"percent" yaml:"percent"`         // packet duplication percentage
+		Correlation float64 `json:"correlation" yaml:"correlation"` // duplication correlation in percentage
+	}
+
+	// CorruptOptions struct define netem corrupt options
+	CorruptOptions struct {
+		Percent     float64 `json:"percent" yaml:"percent"`         // packet corruption percentage
+		Correlation float64 `json:"correlation" yaml:"correlation"` // corruption correlation in percentage
+	}
+)
+
+// parseNetEmArgs function return netem args, netem options come first
+// followed by netem command and it's own options
+func (w Worker) parseNetEmArgs() []string {
+	var (
+		opts = w.netEmOptions
+		args []string
+	)
+
+	if opts.Duration != "" {
+		args = append(args, "--duration", opts.Duration)
+	}
+
+	if opts.Interface != "" {
+		args = append(args, "--interface", opts.Interface)
+	}
+
+	for _, ip := range splitList(opts.TargetIPFilter) {
+		args = append(args, "--target", ip)
+	}
+
+	if opts.TCImage != "" {
+		args = append(args, "--tc-image", opts.TCImage)
+	}
+
+	if opts.PullImage {
+		args = append(args, "--pull-image")
+	}
+
+	args = append(args, string(w.netEmCommand))
+
+	switch w.netEmCommand {
+	case CommandNetEmDelay:
+		args = append(args, opts.Delay.args()...)
+	case CommandNetEmLoss:
+		args = append(args, percentArgs(opts.Loss.Percent, opts.Loss.Correlation)...)
+	case CommandNetEmLossState:
+		args = append(args, opts.LossState.args()...)
+	case CommandNetEmLossGEModel:
+		args = append(args, opts.LossGEModel.args()...)
+	case CommandNetEmRate:
+		args = append(args, opts.Rate.args()...)
+	case CommandNetEmDuplicate:
+		args = append(args, percentArgs(opts.Duplicate.Percent, opts.Duplicate.Correlation)...)
+	case CommandNetEmCorrupt:
+		args = append(args, percentArgs(opts.Corrupt.Percent, opts.Corrupt.Correlation)...)
+	}
+
+	return args
+}
+
+func (o DelayOptions) args() []string {
+	var args []string
+
+	if o.Time > 0 {
+		args = append(args, "--time", strconv.Itoa(o.Time))
+	}
+
+	if o.Jitter > 0 {
+		args = append(args, "--jitter", strconv.Itoa(o.Jitter))
+	}
+
+	if o.Correlation > 0 {
+		args = append(args, "--correlation", strconv.Itoa(o.Correlation))
+	}
+
+	if o.Distribution != "" {
+		args = append(args, "--distribution", o.Distribution)
+	}
+
+	return args
+}
+
+func (o LossStateOptions) args() []string {
+	var args []string
+
+	for _, p := range []struct {
+		flag  string
+		value float64
+	}{
+		{"--p13", o.P13},
+		{"--p31", o.P31},
+		{"--p32", o.P32},
+		{"--p23", o.P23},
+		{"--p14", o.P14},
+	} {
+		if p.value > 0 {
+			args = append(args, p.flag, formatFloat(p.value))
+		}
+	}
+
+	return args
+}
+
+func (o LossGEModelOptions) args() []string {
+	var args []string
+
+	for _, p := range []struct {
+		flag  string
+		value float64
+	}{
+		{"--pg", o.PG},
+		{"--pb", o.PB},
+		{"--one-h", o.H},
+		{"--one-k", o.K},
+	} {
+		if p.value > 0 {
+			args = append(args, p.flag, formatFloat(p.value))
+		}
+	}
+
+	return args
+}
+
+func (o RateOptions) args() []string {
+	var args []string
+
+	if o.Rate != "" {
+		args = append(args, "--rate", o.Rate)
+	}
+
+	if o.PacketOverhead != 0 {
+		args = append(args, "--packetoverhead", strconv.Itoa(o.PacketOverhead))
+	}
+
+	if o.CellSize > 0 {
+		args = append(args, "--cellsize", strconv.Itoa(o.CellSize))
+	}
+
+	if o.CellOverhead != 0 {
+		args = append(args, "--celloverhead", strconv.Itoa(o.CellOverhead))
+	}
+
+	return args
+}
+
+// percentArgs function return args of netem command that
+// only have percent and correlation option, eg : loss, duplicate and corrupt
+func percentArgs(percent, correlation float64) []string {
+	var args []string
+
+	if percent > 0 {
+		args = append(args, "--percent", formatFloat(percent))
+	}
+
+	if correlation > 0 {
+		args = append(args, "--correlation", formatFloat(correlation))
+	}
+
+	return args
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
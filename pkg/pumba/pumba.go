@@ -2,12 +2,15 @@ package pumba
 
 import (
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 
-	"github.com/faruqisan/resilia/pkg/kube"
 	"github.com/google/uuid"
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 const (
@@ -31,6 +34,15 @@ const (
 
 	// CommandPause is worker command to pause container
 	CommandPause WorkerCommandMode = "pause"
+
+	// CommandKill is worker command to kill container with signal
+	CommandKill WorkerCommandMode = "kill"
+
+	// CommandStop is worker command to stop container
+	CommandStop WorkerCommandMode = "stop"
+
+	// CommandRemove is worker command to remove container
+	CommandRemove WorkerCommandMode = "rm"
 )
 
 type (
@@ -43,16 +55,41 @@ type (
 	// Worker struct hold pumba worker data
 	Worker struct {
 		id       string
-		target   string // target pod
+		target   Target
 		interval string
 		mode     WorkerCommandMode
 
+		// modes of options applied to worker, should agree with mode
+		optionModes []WorkerCommandMode
+
+		// target pods resolved when worker run
+		targetNamespace string
+		targetPods      []string
+
+		// extra labels put on worker's daemon set and it's pods
+		labels map[string]string
+
 		// netem related
 		netEmCommand NetEmCommands
 		netEmOptions NetEmOptions
 
 		// pause related
 		pauseOptions PauseOptions
+
+		// kill related
+		killOptions KillOptions
+
+		// stop related
+		stopOptions StopOptions
+
+		// remove related
+		removeOptions RemoveOptions
+
+		// stress related
+		stressOptions StressOptions
+
+		// iptables related
+		ipTablesOptions IPTablesOptions
 	}
 
 	// NetEmCommands type define netem command
@@ -62,34 +99,89 @@ type (
 
 	//NetEmOptions struct define netem options
 	NetEmOptions struct {
-		TCImage        string // Docker image with tc (iproute2 package); try 'gaiadocker/iproute2'
-		Duration       string // network emulation duration; should be smaller than recurrent interval; use with optional unit suffix: 'ms/s/m/h'
-		Interface      string // network interface to apply delay on (default: "eth0")
-		TargetIPFilter string // target IP filter; supports multiple IPs; supports CIDR notation
-		PullImage      bool   // try to pull tc-image
-
-		// loss options
-		LossPercent string
+		TCImage        string `json:"tc_image" yaml:"tc_image"`                 // Docker image with tc (iproute2 package); try 'gaiadocker/iproute2'
+		Duration       string `json:"duration" yaml:"duration"`                 // network emulation duration; should be smaller than recurrent interval; use with optional unit suffix: 'ms/s/m/h'
+		Interface      string `json:"interface" yaml:"interface"`               // network interface to apply delay on (default: "eth0")
+		TargetIPFilter string `json:"target_ip_filter" yaml:"target_ip_filter"` // target IP filter; supports multiple IPs separated by comma; supports CIDR notation
+		PullImage      bool   `json:"pull_image" yaml:"pull_image"`             // try to pull tc-image
+
+		// per command options, only options of worker's netem command used
+		Delay       DelayOptions       `json:"delay" yaml:"delay"`
+		Loss        LossOptions        `json:"loss" yaml:"loss"`
+		LossState   LossStateOptions   `json:"loss_state" yaml:"loss_state"`
+		LossGEModel LossGEModelOptions `json:"loss_gemodel" yaml:"loss_gemodel"`
+		Rate        RateOptions        `json:"rate" yaml:"rate"`
+		Duplicate   DuplicateOptions   `json:"duplicate" yaml:"duplicate"`
+		Corrupt     CorruptOptions     `json:"corrupt" yaml:"corrupt"`
 	}
 
 	// PauseOptions struct define pause options
 	PauseOptions struct {
 		// Duration pause duration: must be shorter than recurrent interval;
 		// use with optional unit suffix: 'ms/s/m/h'
-		Duration string
+		Duration string `json:"duration" yaml:"duration"`
+	}
+
+	// KillOptions struct define kill options
+	KillOptions struct {
+		// Signal termination signal to send to container,
+		// eg : SIGTERM or SIGKILL (default: "SIGKILL")
+		Signal string `json:"signal" yaml:"signal"`
+		// Limit number of container to kill (0: kill all matching)
+		Limit int `json:"limit" yaml:"limit"`
+	}
+
+	// StopOptions struct define stop options
+	StopOptions struct {
+		// Duration stop duration: must be shorter than recurrent interval;
+		// only used with Restart; use with optional unit suffix: 'ms/s/m/h'
+		Duration string `json:"duration" yaml:"duration"`
+		// Restart restart stopped container after Duration
+		Restart bool `json:"restart" yaml:"restart"`
+		// WaitTime seconds to wait for stop before killing container (default 5)
+		WaitTime int `json:"wait_time" yaml:"wait_time"`
+	}
+
+	// RemoveOptions struct define rm options
+	RemoveOptions struct {
+		// Force force the removal of running container (with SIGKILL)
+		Force bool `json:"force" yaml:"force"`
+		// Links remove container links
+		Links bool `json:"links" yaml:"links"`
+		// Volumes remove volumes associated with container
+		Volumes bool `json:"volumes" yaml:"volumes"`
+	}
+
+	// KubeEngine interface define kube engine required contract
+	// this is helping us to mock kube package
+	KubeEngine interface {
+		Namespace() string
+		GetDeploymentSelector(namespace, name string) (string, error)
+		GetRunningPods(namespace, labelSelector string) ([]string, error)
+		CreateDaemonSet(daemonSet *appsv1.DaemonSet) (string, error)
+		GetDaemonSet(name string) (*appsv1.DaemonSet, error)
+		ListDaemonSets(labelSelector string) ([]appsv1.DaemonSet, error)
+		UpdateDaemonSet(daemonSet *appsv1.DaemonSet) error
+		DeleteDaemonSetWithPods(name string) error
 	}
 
 	// Engine struct act as function receiver and hold pumba engine
 	// configuration
 	Engine struct {
-		kubeEngine *kube.Engine
+		kubeEngine KubeEngine
+
+		// mu guard retargets, stop channel of worker's target
+		// resolution by worker's daemon set name
+		mu        sync.Mutex
+		retargets map[string]chan struct{}
 	}
 )
 
 // New function return new engine struct with setuped configuration
-func New(kubeEngine *kube.Engine) *Engine {
+func New(kubeEngine KubeEngine) *Engine {
 	return &Engine{
 		kubeEngine: kubeEngine,
+		retargets:  make(map[string]chan struct{}),
 	}
 }
 
@@ -97,7 +189,7 @@ func New(kubeEngine *kube.Engine) *Engine {
 func (e *Engine) NetEm(netEmCommand NetEmCommands, options NetEmOptions) WorkerOptions {
 	return func(w *Worker) {
 		w.netEmOptions = options
-		w.mode = CommandNetEm
+		w.optionModes = append(w.optionModes, CommandNetEm)
 		w.netEmCommand = netEmCommand
 	}
 }
@@ -106,12 +198,36 @@ func (e *Engine) NetEm(netEmCommand NetEmCommands, options NetEmOptions) WorkerO
 func (e *Engine) Pause(options PauseOptions) WorkerOptions {
 	return func(w *Worker) {
 		w.pauseOptions = options
-		w.mode = CommandPause
+		w.optionModes = append(w.optionModes, CommandPause)
+	}
+}
+
+// Kill function set options to worker to use kill
+func (e *Engine) Kill(options KillOptions) WorkerOptions {
+	return func(w *Worker) {
+		w.killOptions = options
+		w.optionModes = append(w.optionModes, CommandKill)
+	}
+}
+
+// Stop function set options to worker to use stop
+func (e *Engine) Stop(options StopOptions) WorkerOptions {
+	return func(w *Worker) {
+		w.stopOptions = options
+		w.optionModes = append(w.optionModes, CommandStop)
+	}
+}
+
+// Remove function set options to worker to use rm
+func (e *Engine) Remove(options RemoveOptions) WorkerOptions {
+	return func(w *Worker) {
+		w.removeOptions = options
+		w.optionModes = append(w.optionModes, CommandRemove)
 	}
 }
 
 // NewPumbaWorker function will spawn new pumba worker
-func (e *Engine) NewPumbaWorker(target string, interval string, mode WorkerCommandMode, options ...WorkerOptions) Worker {
+func (e *Engine) NewPumbaWorker(target Target, interval string, mode WorkerCommandMode, options ...WorkerOptions) Worker {
 	var (
 		w   Worker
 		uID = uuid.New().ID()
@@ -126,6 +242,11 @@ func (e *Engine) NewPumbaWorker(target string, interval string, mode WorkerComma
 		option(&w)
 	}
 
+	// mode can be omitted when it's clear from given option
+	if w.mode == "" && len(w.optionModes) == 1 {
+		w.mode = w.optionModes[0]
+	}
+
 	return w
 }
 
@@ -134,21 +255,58 @@ func (w *Worker) GetID() string {
 	return w.id
 }
 
+// SetLabels function set extra labels put on worker's daemon set
+// and it's pods, eg : to identify who spawn the worker
+func (w *Worker) SetLabels(labels map[string]string) {
+	w.labels = labels
+}
+
 // RunWorker function will run given worker on k8s cluster
-// and returning daemon set name
+// and returning daemon set name, worker target resolved to
+// matching pods at this moment and resolved again on every
+// worker interval, see keepTargeting
 func (e *Engine) RunWorker(worker Worker) (string, error) {
-	ds := e.createPumbaDaemonSet(worker)
-	_, err := e.kubeEngine.CreateDaemonSet(ds)
+	if err := worker.Validate(); err != nil {
+		return "", err
+	}
+
+	interval, err := time.ParseDuration(worker.interval)
 	if err != nil {
 		return "", err
 	}
 
+	namespace, pods, err := e.resolveTarget(worker.target)
+	if err != nil {
+		return "", err
+	}
+	worker.targetNamespace = namespace
+	worker.targetPods = pods
+
+	ds, err := e.createPumbaDaemonSet(worker)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = e.kubeEngine.CreateDaemonSet(ds)
+	if err != nil {
+		return "", err
+	}
+
+	e.keepTargeting(ds.ObjectMeta.Name, worker.target, interval)
+
 	return ds.ObjectMeta.Name, nil
 }
 
+// StopWorker function will stop worker with given daemon set name
+// chaos injection stopped once all of worker's pods terminated
+func (e *Engine) StopWorker(name string) error {
+	e.stopTargeting(name)
+	return e.kubeEngine.DeleteDaemonSetWithPods(name)
+}
+
 // CreatePumbaDaemonSet function will create k8s daemonset object
 // with given options
-func (e *Engine) createPumbaDaemonSet(worker Worker) *appsv1.DaemonSet {
+func (e *Engine) createPumbaDaemonSet(worker Worker) (*appsv1.DaemonSet, error) {
 	var (
 		d             *appsv1.DaemonSet
 		daemonSetName = fmt.Sprintf("resilience-pumba-%s", worker.GetID())
@@ -156,11 +314,34 @@ func (e *Engine) createPumbaDaemonSet(worker Worker) *appsv1.DaemonSet {
 		volumeName    = "dockersocker"
 		mountPath     = "/var/run/docker.sock"
 		imageName     = "gaiaadm/pumba"
+		// every pumba pod pointed at newly resolved target pods at once
+		maxUnavailable = intstr.FromString("100%")
 	)
 
+	annotations, err := targetAnnotations(worker)
+	if err != nil {
+		return nil, err
+	}
+
+	podLabels := map[string]string{
+		"app":               daemonSetName,
+		"com.gaiaadm.pumba": "true",
+	}
+
+	// pumba daemon set is resilia's own, unlike user's objects
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "resilia",
+	}
+	for k, v := range worker.labels {
+		labels[k] = v
+		podLabels[k] = v
+	}
+
 	d = &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: daemonSetName,
+			Name:        daemonSetName,
+			Labels:      labels,
+			Annotations: annotations,
 		},
 		Spec: appsv1.DaemonSetSpec{
 			Selector: &metav1.LabelSelector{
@@ -168,13 +349,16 @@ func (e *Engine) createPumbaDaemonSet(worker Worker) *appsv1.DaemonSet {
 					"app": daemonSetName,
 				},
 			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+					MaxUnavailable: &maxUnavailable,
+				},
+			},
 			Template: apiv1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Name: daemonSetName,
-					Labels: map[string]string{
-						"app":               daemonSetName,
-						"com.gaiaadm.pumba": "true",
-					},
+					Name:   daemonSetName,
+					Labels: podLabels,
 				},
 				Spec: apiv1.PodSpec{
 					Containers: []apiv1.Container{
@@ -205,18 +389,18 @@ func (e *Engine) createPumbaDaemonSet(worker Worker) *appsv1.DaemonSet {
 		},
 	}
 
-	return d
+	return d, nil
 }
 
 func (w Worker) parseArgs() []string {
 	var (
-		args       []string
-		targetArgs = fmt.Sprintf("io.kubernetes.pod.name=%s", w.target)
+		args []string
 	)
 
 	args = []string{
 		"--log-level", "info",
-		"--label", targetArgs,
+		// skip pod sandbox container, target only pod's containers
+		"--label", "io.kubernetes.docker.type=container",
 		"--interval", w.interval,
 		string(w.mode),
 	}
@@ -226,41 +410,76 @@ func (w Worker) parseArgs() []string {
 		args = append(args, w.parseNetEmArgs()...)
 	case CommandPause:
 		args = append(args, w.parsePauseArgs()...)
+	case CommandKill:
+		args = append(args, w.parseKillArgs()...)
+	case CommandStop:
+		args = append(args, w.parseStopArgs()...)
+	case CommandRemove:
+		args = append(args, w.parseRemoveArgs()...)
+	case CommandStress:
+		args = append(args, w.parseStressArgs()...)
+	case CommandIPTables:
+		args = append(args, w.parseIPTablesArgs()...)
 	}
 
+	args = append(args, containersPattern(w.targetNamespace, w.targetPods))
+
 	return args
 }
 
-func (w Worker) parseNetEmArgs() []string {
-	args := []string{
-		string(w.netEmCommand),
-		"--duration", w.netEmOptions.Duration,
-		"--tc-image", w.netEmOptions.TCImage,
-	}
+func (w Worker) parsePauseArgs() []string {
+	var (
+		args []string
+	)
 
-	if w.netEmOptions.Interface != "" {
-		args = append(args, "-i", w.netEmOptions.Interface)
+	if w.pauseOptions.Duration != "" {
+		args = append(args, "-d", w.pauseOptions.Duration)
 	}
 
-	if w.netEmOptions.TargetIPFilter != "" {
-		args = append(args, "-t", w.netEmOptions.TargetIPFilter)
+	return args
+}
+
+func (w Worker) parseKillArgs() []string {
+	var (
+		args []string
+	)
+
+	if w.killOptions.Signal != "" {
+		args = append(args, "--signal", w.killOptions.Signal)
 	}
 
-	if w.netEmOptions.LossPercent != "" {
-		args = append(args, "--percent", w.netEmOptions.LossPercent)
+	if w.killOptions.Limit > 0 {
+		args = append(args, "--limit", strconv.Itoa(w.killOptions.Limit))
 	}
 
 	return args
 }
 
-func (w Worker) parsePauseArgs() []string {
+func (w Worker) parseStopArgs() []string {
 	var (
 		args []string
 	)
 
-	if w.pauseOptions.Duration != "" {
-		args = append(args, "-d", w.pauseOptions.Duration)
+	if w.stopOptions.Restart {
+		args = append(args, "--restart")
+
+		if w.stopOptions.Duration != "" {
+			args = append(args, "--duration", w.stopOptions.Duration)
+		}
+	}
+
+	if w.stopOptions.WaitTime > 0 {
+		args = append(args, "--time", strconv.Itoa(w.stopOptions.WaitTime))
 	}
 
 	return args
 }
+
+func (w Worker) parseRemoveArgs() []string {
+	// pumba default force and volumes to true, so always set them explicitly
+	return []string{
+		"--force=" + strconv.FormatBool(w.removeOptions.Force),
+		"--links=" + strconv.FormatBool(w.removeOptions.Links),
+		"--volumes=" + strconv.FormatBool(w.removeOptions.Volumes),
+	}
+}
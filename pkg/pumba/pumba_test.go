@@ -0,0 +1,204 @@
+package pumba
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWorkerParseArgs(t *testing.T) {
+	var (
+		e      = New(nil)
+		target = "re2:^k8s_[^_]+_(redis-0|redis-1)_default_"
+	)
+
+	tests := []struct {
+		name    string
+		mode    WorkerCommandMode
+		options []WorkerOptions
+		want    []string
+	}{
+		{
+			name: "netem delay",
+			mode: CommandNetEm,
+			options: []WorkerOptions{e.NetEm(CommandNetEmDelay, NetEmOptions{
+				TCImage:        "gaiadocker/iproute2",
+				Duration:       "30s",
+				Interface:      "eth1",
+				TargetIPFilter: "10.0.0.1, 10.1.0.0/16",
+				PullImage:      true,
+				Delay:          DelayOptions{Time: 3000, Jitter: 40, Correlation: 25, Distribution: "normal"},
+			})},
+			want: []string{
+				"--duration", "30s", "--interface", "eth1",
+				"--target", "10.0.0.1", "--target", "10.1.0.0/16",
+				"--tc-image", "gaiadocker/iproute2", "--pull-image",
+				"delay", "--time", "3000", "--jitter", "40", "--correlation", "25", "--distribution", "normal",
+			},
+		},
+		{
+			name: "netem loss",
+			mode: CommandNetEm,
+			options: []WorkerOptions{e.NetEm(CommandNetEmLoss, NetEmOptions{
+				Duration: "30s",
+				Loss:     LossOptions{Percent: 12.5, Correlation: 5},
+			})},
+			want: []string{"--duration", "30s", "loss", "--percent", "12.5", "--correlation", "5"},
+		},
+		{
+			name: "netem loss state",
+			mode: CommandNetEm,
+			options: []WorkerOptions{e.NetEm(CommandNetEmLossState, NetEmOptions{
+				Duration:  "30s",
+				LossState: LossStateOptions{P13: 10, P31: 70, P14: 0.5},
+			})},
+			want: []string{"--duration", "30s", "loss-state", "--p13", "10", "--p31", "70", "--p14", "0.5"},
+		},
+		{
+			name: "netem loss gemodel",
+			mode: CommandNetEm,
+			options: []WorkerOptions{e.NetEm(CommandNetEmLossGEModel, NetEmOptions{
+				Duration:    "30s",
+				LossGEModel: LossGEModelOptions{PG: 5, PB: 90, H: 100, K: 0.1},
+			})},
+			want: []string{"--duration", "30s", "loss-gemodel", "--pg", "5", "--pb", "90", "--one-h", "100", "--one-k", "0.1"},
+		},
+		{
+			name: "netem rate",
+			mode: CommandNetEm,
+			options: []WorkerOptions{e.NetEm(CommandNetEmRate, NetEmOptions{
+				Duration: "30s",
+				Rate:     RateOptions{Rate: "100kbit", PacketOverhead: -4, CellSize: 53, CellOverhead: 5},
+			})},
+			want: []string{"--duration", "30s", "rate", "--rate", "100kbit", "--packetoverhead", "-4", "--cellsize", "53", "--celloverhead", "5"},
+		},
+		{
+			name: "netem duplicate",
+			mode: CommandNetEm,
+			options: []WorkerOptions{e.NetEm(CommandNetEmDuplicate, NetEmOptions{
+				Duration:  "30s",
+				Duplicate: DuplicateOptions{Percent: 1},
+			})},
+			want: []string{"--duration", "30s", "duplicate", "--percent", "1"},
+		},
+		{
+			name: "netem corrupt",
+			mode: CommandNetEm,
+			options: []WorkerOptions{e.NetEm(CommandNetEmCorrupt, NetEmOptions{
+				Duration: "30s",
+				Corrupt:  CorruptOptions{Percent: 0.1, Correlation: 50},
+			})},
+			want: []string{"--duration", "30s", "corrupt", "--percent", "0.1", "--correlation", "50"},
+		},
+		{
+			name:    "pause",
+			mode:    CommandPause,
+			options: []WorkerOptions{e.Pause(PauseOptions{Duration: "15s"})},
+			want:    []string{"-d", "15s"},
+		},
+		{
+			name:    "kill",
+			mode:    CommandKill,
+			options: []WorkerOptions{e.Kill(KillOptions{Signal: "SIGTERM", Limit: 2})},
+			want:    []string{"--signal", "SIGTERM", "--limit", "2"},
+		},
+		{
+			name:    "kill default",
+			mode:    CommandKill,
+			options: []WorkerOptions{e.Kill(KillOptions{})},
+		},
+		{
+			name:    "stop with restart",
+			mode:    CommandStop,
+			options: []WorkerOptions{e.Stop(StopOptions{Duration: "20s", Restart: true, WaitTime: 3})},
+			want:    []string{"--restart", "--duration", "20s", "--time", "3"},
+		},
+		{
+			name:    "stop without restart ignore duration",
+			mode:    CommandStop,
+			options: []WorkerOptions{e.Stop(StopOptions{Duration: "20s"})},
+		},
+		{
+			name:    "rm",
+			mode:    CommandRemove,
+			options: []WorkerOptions{e.Remove(RemoveOptions{Links: true})},
+			want:    []string{"--force=false", "--links=true", "--volumes=false"},
+		},
+		{
+			name: "stress",
+			mode: CommandStress,
+			options: []WorkerOptions{e.Stress(StressOptions{
+				Duration:  "30s",
+				PullImage: true,
+				Stressors: StressorSpec{CPUWorkers: 2, CPULoad: 80, VMWorkers: 1, VMBytes: "256M", IOWorkers: 1, Timeout: "25s"},
+			})},
+			want: []string{
+				"--duration", "30s", "--stress-image", DefaultStressImage, "--pull-image",
+				"--stressors", "--cpu 2 --cpu-load 80 --vm 1 --vm-bytes 256M --io 1 --timeout 25s",
+			},
+		},
+		{
+			name: "stress custom image",
+			mode: CommandStress,
+			options: []WorkerOptions{e.Stress(StressOptions{
+				Image:     "stress-ng:local",
+				Duration:  "30s",
+				Stressors: StressorSpec{IOWorkers: 4},
+			})},
+			want: []string{"--duration", "30s", "--stress-image", "stress-ng:local", "--stressors", "--io 4"},
+		},
+		{
+			name: "iptables random",
+			mode: CommandIPTables,
+			options: []WorkerOptions{e.IPTables(IPTablesOptions{
+				Image:       "biarca/iptables",
+				Duration:    "30s",
+				Interface:   "eth0",
+				Protocol:    "tcp",
+				Source:      "10.0.0.1",
+				Destination: "10.0.0.2,10.0.0.3",
+				SrcPort:     "8080",
+				DstPort:     "6379",
+				PullImage:   true,
+				Probability: 0.25,
+			})},
+			want: []string{
+				"--duration", "30s", "--interface", "eth0", "--protocol", "tcp",
+				"--source", "10.0.0.1", "--destination", "10.0.0.2", "--destination", "10.0.0.3",
+				"--src-port", "8080", "--dst-port", "6379", "--iptables-image", "biarca/iptables", "--pull-image",
+				"loss", "--mode", "random", "--probability", "0.25",
+			},
+		},
+		{
+			name: "iptables nth",
+			mode: CommandIPTables,
+			options: []WorkerOptions{e.IPTables(IPTablesOptions{
+				Duration: "30s",
+				Mode:     IPTablesLossNth,
+				Every:    3,
+				Packet:   1,
+			})},
+			want: []string{"--duration", "30s", "loss", "--mode", "nth", "--every", "3", "--packet", "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := e.NewPumbaWorker(Target{Deployment: "redis"}, "1m", tt.mode, tt.options...)
+			w.targetNamespace = "default"
+			w.targetPods = []string{"redis-0", "redis-1"}
+
+			want := []string{
+				"--log-level", "info",
+				"--label", "io.kubernetes.docker.type=container",
+				"--interval", "1m",
+				string(tt.mode),
+			}
+			want = append(want, tt.want...)
+			want = append(want, target)
+
+			if got := w.parseArgs(); !reflect.DeepEqual(got, want) {
+				t.Errorf("parseArgs()\n got = %q\nwant = %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package pumba
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// targetAnnotation is worker's daemon set annotation holding worker target as json
+	// so worker target still resolved once resilia restarted
+	targetAnnotation = "resilia.io/target"
+	// intervalAnnotation is worker's daemon set annotation holding worker interval
+	intervalAnnotation = "resilia.io/interval"
+	// workersSelector is label selector of daemon sets spawned by pumba worker
+	workersSelector = "app.kubernetes.io/managed-by=resilia"
+)
+
+// targetAnnotations function return annotations of worker's daemon set
+// needed to resolve worker target again
+func targetAnnotations(worker Worker) (map[string]string, error) {
+	target, err := json.Marshal(worker.target)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		targetAnnotation:   string(target),
+		intervalAnnotation: worker.interval,
+	}, nil
+}
+
+// keepTargeting function resolve target of worker with given daemon set name
+// again on every worker interval until worker stopped, so pods replaced
+// while chaos running keep being targeted and pods picked again per injection
+func (e *Engine) keepTargeting(name string, target Target, interval time.Duration) {
+	stop := make(chan struct{})
+
+	e.mu.Lock()
+	if old, ok := e.retargets[name]; ok {
+		close(old)
+	}
+	e.retargets[name] = stop
+	e.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			_, err := e.retarget(name, target)
+			switch {
+			case apierrors.IsNotFound(err):
+				// worker stopped by other resilia, eg : sharing the same run store
+				e.mu.Lock()
+				if e.retargets[name] == stop {
+					delete(e.retargets, name)
+				}
+				e.mu.Unlock()
+				return
+			case err != nil:
+				// worker keep hitting previously resolved pods
+				log.Printf("fail to resolve target of worker %s: %s", name, err)
+			}
+		}
+	}()
+}
+
+// stopTargeting function stop target resolution of worker with given daemon set name
+func (e *Engine) stopTargeting(name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if stop, ok := e.retargets[name]; ok {
+		close(stop)
+		delete(e.retargets, name)
+	}
+}
+
+// retarget function resolve given target of worker with given daemon set name
+// and point worker's pumba at resolved pods, daemon set pods replaced and
+// chaos injected right away once the pods changed, returning true in that case
+func (e *Engine) retarget(name string, target Target) (bool, error) {
+	ds, err := e.kubeEngine.GetDaemonSet(name)
+	if err != nil {
+		return false, err
+	}
+
+	namespace, pods, err := e.resolveTarget(target)
+	if err != nil {
+		return false, err
+	}
+
+	containers := ds.Spec.Template.Spec.Containers
+	if len(containers) == 0 || len(containers[0].Args) == 0 {
+		return false, fmt.Errorf("daemon set %s has no pumba container", name)
+	}
+
+	// containers pattern always the last pumba argument
+	var (
+		args    = containers[0].Args
+		pattern = containersPattern(namespace, pods)
+	)
+
+	if args[len(args)-1] == pattern {
+		return false, nil
+	}
+
+	args[len(args)-1] = pattern
+	return true, e.kubeEngine.UpdateDaemonSet(ds)
+}
+
+// ResumeWorkers function keep resolving target of every running worker
+// meant to be called on startup, so workers spawned before restart keep
+// following their target pods, returning number of resumed workers
+func (e *Engine) ResumeWorkers() (int, error) {
+	daemonSets, err := e.kubeEngine.ListDaemonSets(workersSelector)
+	if err != nil {
+		return 0, err
+	}
+
+	var resumed int
+	for _, ds := range daemonSets {
+		// worker being stopped
+		if ds.DeletionTimestamp != nil {
+			continue
+		}
+
+		target, interval, err := workerTarget(ds)
+		if err != nil {
+			log.Printf("fail to resume worker %s: %s", ds.Name, err)
+			continue
+		}
+
+		e.keepTargeting(ds.Name, target, interval)
+		resumed++
+	}
+
+	return resumed, nil
+}
+
+// workerTarget function return target and interval of worker
+// kept on given worker's daemon set annotations
+func workerTarget(ds appsv1.DaemonSet) (Target, time.Duration, error) {
+	var target Target
+
+	raw, ok := ds.Annotations[targetAnnotation]
+	if !ok {
+		return target, 0, fmt.Errorf("missing %s annotation", targetAnnotation)
+	}
+
+	if err := json.Unmarshal([]byte(raw), &target); err != nil {
+		return target, 0, fmt.Errorf("%s annotation: %w", targetAnnotation, err)
+	}
+
+	interval, err := time.ParseDuration(ds.Annotations[intervalAnnotation])
+	if err != nil {
+		return target, 0, fmt.Errorf("%s annotation: %w", intervalAnnotation, err)
+	}
+
+	if interval <= 0 {
+		return target, 0, fmt.Errorf("%s annotation: must be positive", intervalAnnotation)
+	}
+
+	return target, interval, nil
+}
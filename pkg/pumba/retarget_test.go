@@ -0,0 +1,311 @@
+package pumba
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeKube struct is kube engine of single namespace cluster
+// whose running pods set by test
+type fakeKube struct {
+	mu         sync.Mutex
+	pods       map[string][]string // running pods by label selector
+	daemonSets map[string]*appsv1.DaemonSet
+}
+
+func newFakeKube() *fakeKube {
+	return &fakeKube{
+		pods:       make(map[string][]string),
+		daemonSets: make(map[string]*appsv1.DaemonSet),
+	}
+}
+
+func (k *fakeKube) setPods(selector string, pods ...string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.pods[selector] = pods
+}
+
+// pattern function return containers pattern of given daemon set's pumba
+func (k *fakeKube) pattern(name string) string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	ds, ok := k.daemonSets[name]
+	if !ok {
+		return ""
+	}
+	args := ds.Spec.Template.Spec.Containers[0].Args
+	return args[len(args)-1]
+}
+
+func (k *fakeKube) Namespace() string { return "default" }
+
+func (k *fakeKube) GetDeploymentSelector(namespace, name string) (string, error) {
+	return "app=" + name, nil
+}
+
+func (k *fakeKube) GetRunningPods(namespace, labelSelector string) ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return append([]string(nil), k.pods[labelSelector]...), nil
+}
+
+func (k *fakeKube) CreateDaemonSet(daemonSet *appsv1.DaemonSet) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.daemonSets[daemonSet.Name] = daemonSet.DeepCopy()
+	return daemonSet.Name, nil
+}
+
+func (k *fakeKube) GetDaemonSet(name string) (*appsv1.DaemonSet, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	ds, ok := k.daemonSets[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "daemonsets"}, name)
+	}
+	return ds.DeepCopy(), nil
+}
+
+func (k *fakeKube) ListDaemonSets(labelSelector string) ([]appsv1.DaemonSet, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var daemonSets []appsv1.DaemonSet
+	for _, ds := range k.daemonSets {
+		daemonSets = append(daemonSets, *ds.DeepCopy())
+	}
+	return daemonSets, nil
+}
+
+func (k *fakeKube) UpdateDaemonSet(daemonSet *appsv1.DaemonSet) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if _, ok := k.daemonSets[daemonSet.Name]; !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "daemonsets"}, daemonSet.Name)
+	}
+	k.daemonSets[daemonSet.Name] = daemonSet.DeepCopy()
+	return nil
+}
+
+func (k *fakeKube) DeleteDaemonSetWithPods(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.daemonSets, name)
+	return nil
+}
+
+func (e *Engine) isTargeting(name string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, ok := e.retargets[name]
+	return ok
+}
+
+// eventually function check given condition hold within a second
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRunWorkerFollowTargetPods(t *testing.T) {
+	var (
+		kube = newFakeKube()
+		e    = New(kube)
+	)
+	kube.setPods("app=redis", "redis-7d9f-b", "redis-7d9f-a")
+
+	// interval long enough to never tick, target resolved by test
+	w := e.NewPumbaWorker(Target{Deployment: "redis"}, "1h", CommandKill, e.Kill(KillOptions{}))
+	name, err := e.RunWorker(w)
+	if err != nil {
+		t.Fatalf("RunWorker() error = %v", err)
+	}
+	defer e.stopTargeting(name)
+
+	if got, want := kube.pattern(name), "re2:^k8s_[^_]+_(redis-7d9f-a|redis-7d9f-b)_default_"; got != want {
+		t.Fatalf("pattern = %q, want %q", got, want)
+	}
+
+	ds, _ := kube.GetDaemonSet(name)
+	if target, interval, err := workerTarget(*ds); err != nil || target != w.target || interval != time.Hour {
+		t.Errorf("workerTarget() = %+v, %s, %v, want %+v, 1h", target, interval, err, w.target)
+	}
+
+	steps := []struct {
+		name    string
+		pods    []string
+		updated bool
+		pattern string
+	}{
+		{
+			name:    "pod replaced",
+			pods:    []string{"redis-7d9f-b", "redis-7d9f-c"},
+			updated: true,
+			pattern: "re2:^k8s_[^_]+_(redis-7d9f-b|redis-7d9f-c)_default_",
+		},
+		{
+			name:    "same pods listed in other order",
+			pods:    []string{"redis-7d9f-c", "redis-7d9f-b"},
+			pattern: "re2:^k8s_[^_]+_(redis-7d9f-b|redis-7d9f-c)_default_",
+		},
+		{
+			name:    "rolled out",
+			pods:    []string{"redis-5c2e-a"},
+			updated: true,
+			pattern: "re2:^k8s_[^_]+_(redis-5c2e-a)_default_",
+		},
+	}
+
+	for _, step := range steps {
+		kube.setPods("app=redis", step.pods...)
+
+		updated, err := e.retarget(name, w.target)
+		if err != nil {
+			t.Fatalf("%s: retarget() error = %v", step.name, err)
+		}
+
+		if updated != step.updated || kube.pattern(name) != step.pattern {
+			t.Errorf("%s: retarget() = %v, pattern = %q, want %v, %q", step.name, updated, kube.pattern(name), step.updated, step.pattern)
+		}
+	}
+
+	// matching pods all gone, worker keep previous pods
+	kube.setPods("app=redis")
+	if _, err := e.retarget(name, w.target); err == nil {
+		t.Error("retarget() without matching pods want error")
+	}
+
+	if err := e.StopWorker(name); err != nil {
+		t.Fatal(err)
+	}
+
+	if e.isTargeting(name) {
+		t.Error("target still resolved after worker stopped")
+	}
+}
+
+func TestRetargetPickPerInjection(t *testing.T) {
+	var (
+		kube   = newFakeKube()
+		e      = New(kube)
+		target = Target{LabelSelector: "app=redis", Pick: PickRandom, Count: 1}
+		pods   []string
+	)
+
+	for i := 0; i < 10; i++ {
+		pods = append(pods, fmt.Sprintf("redis-%d", i))
+	}
+	kube.setPods("app=redis", pods...)
+
+	w := e.NewPumbaWorker(target, "1h", CommandKill, e.Kill(KillOptions{}))
+	name, err := e.RunWorker(w)
+	if err != nil {
+		t.Fatalf("RunWorker() error = %v", err)
+	}
+	defer e.stopTargeting(name)
+
+	picked := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		if _, err := e.retarget(name, target); err != nil {
+			t.Fatalf("retarget() error = %v", err)
+		}
+
+		pattern := kube.pattern(name)
+		if strings.Contains(pattern, "|") {
+			t.Fatalf("pattern = %q, want single pod", pattern)
+		}
+		picked[pattern] = true
+	}
+
+	if len(picked) < 2 {
+		t.Errorf("same pod picked on every injection: %v", picked)
+	}
+}
+
+func TestKeepTargeting(t *testing.T) {
+	var (
+		kube   = newFakeKube()
+		e      = New(kube)
+		target = Target{Pod: "redis-0"}
+	)
+
+	w := e.NewPumbaWorker(target, "1h", CommandKill, e.Kill(KillOptions{}))
+	name, err := e.RunWorker(w)
+	if err != nil {
+		t.Fatalf("RunWorker() error = %v", err)
+	}
+
+	e.keepTargeting(name, Target{Pod: "redis-1"}, 5*time.Millisecond)
+
+	eventually(t, "worker pointed at resolved pod", func() bool {
+		return kube.pattern(name) == "re2:^k8s_[^_]+_(redis-1)_default_"
+	})
+
+	// worker stopped by other resilia
+	kube.DeleteDaemonSetWithPods(name)
+
+	eventually(t, "target resolution of removed worker stopped", func() bool {
+		return !e.isTargeting(name)
+	})
+}
+
+func TestResumeWorkers(t *testing.T) {
+	var (
+		kube = newFakeKube()
+		e    = New(kube)
+		now  = metav1.Now()
+	)
+
+	w := e.NewPumbaWorker(Target{Pod: "redis-0"}, "1h", CommandKill, e.Kill(KillOptions{}))
+	ds, err := e.createPumbaDaemonSet(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stopping := ds.DeepCopy()
+	stopping.Name = "stopping"
+	stopping.DeletionTimestamp = &now
+
+	unannotated := ds.DeepCopy()
+	unannotated.Name = "unannotated"
+	unannotated.Annotations = nil
+
+	for _, d := range []*appsv1.DaemonSet{ds, stopping, unannotated} {
+		kube.CreateDaemonSet(d)
+	}
+
+	resumed, err := e.ResumeWorkers()
+	if err != nil {
+		t.Fatalf("ResumeWorkers() error = %v", err)
+	}
+	defer e.stopTargeting(ds.Name)
+
+	if resumed != 1 {
+		t.Errorf("ResumeWorkers() = %d, want 1", resumed)
+	}
+
+	for name, want := range map[string]bool{ds.Name: true, "stopping": false, "unannotated": false} {
+		if got := e.isTargeting(name); got != want {
+			t.Errorf("worker %s target resolved = %v, want %v", name, got, want)
+		}
+	}
+}
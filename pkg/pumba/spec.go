@@ -0,0 +1,96 @@
+package pumba
+
+type (
+	// WorkerSpec struct define serializable pumba worker, it's stored
+	// with suite and turned into worker on every run, only options of
+	// worker mode should be set, mode can be omitted when single option set
+	WorkerSpec struct {
+		Target       Target            `json:"target" yaml:"target"`
+		Interval     string            `json:"interval" yaml:"interval"`
+		Mode         WorkerCommandMode `json:"mode,omitempty" yaml:"mode,omitempty"`
+		NetEmCommand NetEmCommands     `json:"netem_command,omitempty" yaml:"netem_command,omitempty"`
+
+		NetEm    *NetEmOptions    `json:"netem,omitempty" yaml:"netem,omitempty"`
+		Pause    *PauseOptions    `json:"pause,omitempty" yaml:"pause,omitempty"`
+		Kill     *KillOptions     `json:"kill,omitempty" yaml:"kill,omitempty"`
+		Stop     *StopOptions     `json:"stop,omitempty" yaml:"stop,omitempty"`
+		Remove   *RemoveOptions   `json:"rm,omitempty" yaml:"rm,omitempty"`
+		Stress   *StressOptions   `json:"stress,omitempty" yaml:"stress,omitempty"`
+		IPTables *IPTablesOptions `json:"iptables,omitempty" yaml:"iptables,omitempty"`
+	}
+)
+
+// NewPumbaWorkerFromSpec function return new pumba worker from given spec
+// every worker get it's own id, so the same spec can be run repeatedly
+func (e *Engine) NewPumbaWorkerFromSpec(spec WorkerSpec) Worker {
+	var options []WorkerOptions
+
+	if spec.NetEm != nil {
+		options = append(options, e.NetEm(spec.NetEmCommand, *spec.NetEm))
+	}
+
+	if spec.Pause != nil {
+		options = append(options, e.Pause(*spec.Pause))
+	}
+
+	if spec.Kill != nil {
+		options = append(options, e.Kill(*spec.Kill))
+	}
+
+	if spec.Stop != nil {
+		options = append(options, e.Stop(*spec.Stop))
+	}
+
+	if spec.Remove != nil {
+		options = append(options, e.Remove(*spec.Remove))
+	}
+
+	if spec.Stress != nil {
+		options = append(options, e.Stress(*spec.Stress))
+	}
+
+	if spec.IPTables != nil {
+		options = append(options, e.IPTables(*spec.IPTables))
+	}
+
+	return e.NewPumbaWorker(spec.Target, spec.Interval, spec.Mode, options...)
+}
+
+// Spec function return serializable spec of worker
+// options applied to worker kept even when they disagree with mode
+func (w Worker) Spec() WorkerSpec {
+	spec := WorkerSpec{
+		Target:   w.target,
+		Interval: w.interval,
+		Mode:     w.mode,
+	}
+
+	for _, optionMode := range w.optionModes {
+		switch optionMode {
+		case CommandNetEm:
+			netEmOptions := w.netEmOptions
+			spec.NetEm = &netEmOptions
+			spec.NetEmCommand = w.netEmCommand
+		case CommandPause:
+			pauseOptions := w.pauseOptions
+			spec.Pause = &pauseOptions
+		case CommandKill:
+			killOptions := w.killOptions
+			spec.Kill = &killOptions
+		case CommandStop:
+			stopOptions := w.stopOptions
+			spec.Stop = &stopOptions
+		case CommandRemove:
+			removeOptions := w.removeOptions
+			spec.Remove = &removeOptions
+		case CommandStress:
+			stressOptions := w.stressOptions
+			spec.Stress = &stressOptions
+		case CommandIPTables:
+			ipTablesOptions := w.ipTablesOptions
+			spec.IPTables = &ipTablesOptions
+		}
+	}
+
+	return spec
+}
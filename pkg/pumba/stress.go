@@ -0,0 +1,103 @@
+package pumba
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// CommandStress is worker command to stress container resources
+	// using stress-ng running on target container's cgroup
+	CommandStress WorkerCommandMode = "stress"
+
+	// DefaultStressImage is docker image with stress-ng used by stress worker
+	DefaultStressImage = "alexeiled/stress-ng:latest-ubuntu"
+)
+
+type (
+	// StressOptions struct define stress options
+	StressOptions struct {
+		// Image docker image with stress-ng (default: DefaultStressImage)
+		Image string `json:"image" yaml:"image"`
+		// Duration stress duration: must be shorter than recurrent interval;
+		// use with optional unit suffix: 'ms/s/m/h'
+		Duration string `json:"duration" yaml:"duration"`
+		// PullImage try to pull stress image
+		PullImage bool `json:"pull_image" yaml:"pull_image"`
+		// Stressors define stress-ng stressors to run
+		Stressors StressorSpec `json:"stressors" yaml:"stressors"`
+	}
+
+	// StressorSpec struct define stress-ng stressors
+	StressorSpec struct {
+		CPUWorkers int    `json:"cpu_workers" yaml:"cpu_workers"` // number of workers spinning on cpu, 0 disable cpu stressor
+		CPULoad    int    `json:"cpu_load" yaml:"cpu_load"`       // load percentage of each cpu worker
+		VMWorkers  int    `json:"vm_workers" yaml:"vm_workers"`   // number of workers spinning on memory, 0 disable vm stressor
+		VMBytes    string `json:"vm_bytes" yaml:"vm_bytes"`       // memory allocated per vm worker, eg : 256M or 80%
+		IOWorkers  int    `json:"io_workers" yaml:"io_workers"`   // number of workers spinning on sync(), 0 disable io stressor
+		Timeout    string `json:"timeout" yaml:"timeout"`         // stop stressors after timeout, eg : 60s
+	}
+)
+
+// Stress function set options to worker to use stress
+func (e *Engine) Stress(options StressOptions) WorkerOptions {
+	return func(w *Worker) {
+		w.stressOptions = options
+		w.optionModes = append(w.optionModes, CommandStress)
+	}
+}
+
+// String function return stressors as stress-ng args
+func (s StressorSpec) String() string {
+	var args []string
+
+	if s.CPUWorkers > 0 {
+		args = append(args, "--cpu", strconv.Itoa(s.CPUWorkers))
+		if s.CPULoad > 0 {
+			args = append(args, "--cpu-load", strconv.Itoa(s.CPULoad))
+		}
+	}
+
+	if s.VMWorkers > 0 {
+		args = append(args, "--vm", strconv.Itoa(s.VMWorkers))
+		if s.VMBytes != "" {
+			args = append(args, "--vm-bytes", s.VMBytes)
+		}
+	}
+
+	if s.IOWorkers > 0 {
+		args = append(args, "--io", strconv.Itoa(s.IOWorkers))
+	}
+
+	if s.Timeout != "" {
+		args = append(args, "--timeout", s.Timeout)
+	}
+
+	return strings.Join(args, " ")
+}
+
+func (w Worker) parseStressArgs() []string {
+	var (
+		opts  = w.stressOptions
+		image = opts.Image
+		args  []string
+	)
+
+	if image == "" {
+		image = DefaultStressImage
+	}
+
+	if opts.Duration != "" {
+		args = append(args, "--duration", opts.Duration)
+	}
+
+	args = append(args, "--stress-image", image)
+
+	if opts.PullImage {
+		args = append(args, "--pull-image")
+	}
+
+	args = append(args, "--stressors", opts.Stressors.String())
+
+	return args
+}
@@ -0,0 +1,139 @@
+package pumba
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// PickAll target every matching pods
+	PickAll TargetPickMode = "all"
+	// PickRandom target random n (Target.Count) of matching pods
+	PickRandom TargetPickMode = "random"
+	// PickPercent target random percentage (Target.Percent) of matching pods
+	PickPercent TargetPickMode = "percent"
+)
+
+type (
+	// TargetPickMode type define how target pods picked from matching pods
+	TargetPickMode string
+
+	// Target struct define pods targeted by worker, resolved to pods
+	// when worker run and again on every worker interval, so pods picked
+	// per injection, set only one of Pod, Deployment or LabelSelector,
+	// when none set every pod on Namespace targeted
+	Target struct {
+		Pod           string `json:"pod,omitempty" yaml:"pod,omitempty"`                       // exact pod name
+		Deployment    string `json:"deployment,omitempty" yaml:"deployment,omitempty"`         // pods matching deployment's selector
+		LabelSelector string `json:"label_selector,omitempty" yaml:"label_selector,omitempty"` // k8s label selector, eg : app=redis,tier!=cache
+		Namespace     string `json:"namespace,omitempty" yaml:"namespace,omitempty"`           // namespace of target pods, default to kube engine namespace
+
+		Pick    TargetPickMode `json:"pick,omitempty" yaml:"pick,omitempty"`       // how matching pods picked (default all)
+		Count   int            `json:"count,omitempty" yaml:"count,omitempty"`     // number of pods picked on random mode
+		Percent int            `json:"percent,omitempty" yaml:"percent,omitempty"` // percentage of pods picked on percent mode
+	}
+)
+
+// PodTarget function return target of single pod with given name
+func PodTarget(name string) Target {
+	return Target{Pod: name}
+}
+
+// TargetPods function return namespace and names of every running pod
+// matching given target at the moment, before any of them picked
+func (e *Engine) TargetPods(target Target) (string, []string, error) {
+	var (
+		namespace = target.Namespace
+		selector  = target.LabelSelector
+		pods      []string
+		err       error
+	)
+
+	if namespace == "" {
+		namespace = e.kubeEngine.Namespace()
+	}
+
+	switch {
+	case target.Pod != "":
+		pods = []string{target.Pod}
+	case target.Deployment != "":
+		selector, err = e.kubeEngine.GetDeploymentSelector(namespace, target.Deployment)
+		if err != nil {
+			return namespace, pods, err
+		}
+		fallthrough
+	default:
+		pods, err = e.kubeEngine.GetRunningPods(namespace, selector)
+	}
+
+	return namespace, pods, err
+}
+
+// resolveTarget function return namespace and names of pods
+// targeted by given target at the moment
+func (e *Engine) resolveTarget(target Target) (string, []string, error) {
+	namespace, pods, err := e.TargetPods(target)
+	if err != nil {
+		return namespace, pods, err
+	}
+
+	if len(pods) == 0 {
+		return namespace, pods, errors.New("no running pod match worker target")
+	}
+
+	pods, err = pickPods(pods, target)
+	return namespace, pods, err
+}
+
+// pickPods function pick target pods from matching pods by target pick mode
+func pickPods(pods []string, target Target) ([]string, error) {
+	var n int
+
+	switch target.Pick {
+	case "", PickAll:
+		return pods, nil
+	case PickRandom:
+		n = target.Count
+	case PickPercent:
+		n = int(math.Ceil(float64(len(pods)) * float64(target.Percent) / 100))
+	default:
+		return nil, fmt.Errorf("unknown target pick mode %q", target.Pick)
+	}
+
+	if n < 1 {
+		n = 1
+	}
+	if n > len(pods) {
+		n = len(pods)
+	}
+
+	picked := make([]string, len(pods))
+	copy(picked, pods)
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	r.Shuffle(len(picked), func(i, j int) {
+		picked[i], picked[j] = picked[j], picked[i]
+	})
+
+	return picked[:n], nil
+}
+
+// containersPattern function return pumba re2 pattern that match
+// docker containers of given pods, kubelet name container as
+// k8s_<container>_<pod>_<namespace>_<pod uid>_<attempt>
+// pods sorted, so the same pods always give the same pattern
+func containersPattern(namespace string, pods []string) string {
+	quoted := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		quoted = append(quoted, regexp.QuoteMeta(pod))
+	}
+	sort.Strings(quoted)
+
+	return fmt.Sprintf("re2:^k8s_[^_]+_(%s)_%s_", strings.Join(quoted, "|"), regexp.QuoteMeta(namespace))
+}
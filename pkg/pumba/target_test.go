@@ -0,0 +1,62 @@
+package pumba
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTargetPods(t *testing.T) {
+	kube := newFakeKube()
+	kube.setPods("app=redis", "redis-0", "redis-1", "redis-2")
+	kube.setPods("", "redis-0", "redis-1", "redis-2", "web-0")
+
+	tests := []struct {
+		name      string
+		target    Target
+		namespace string
+		pods      []string
+	}{
+		{
+			name:      "pod",
+			target:    Target{Pod: "redis-1", Namespace: "cache"},
+			namespace: "cache",
+			pods:      []string{"redis-1"},
+		},
+		{
+			name:      "deployment",
+			target:    Target{Deployment: "redis"},
+			namespace: "default",
+			pods:      []string{"redis-0", "redis-1", "redis-2"},
+		},
+		{
+			// every matching pod returned, pick left to injection
+			name:      "label selector picking one",
+			target:    Target{LabelSelector: "app=redis", Pick: PickRandom, Count: 1},
+			namespace: "default",
+			pods:      []string{"redis-0", "redis-1", "redis-2"},
+		},
+		{
+			name:      "whole namespace",
+			namespace: "default",
+			pods:      []string{"redis-0", "redis-1", "redis-2", "web-0"},
+		},
+		{
+			name:      "nothing running",
+			target:    Target{LabelSelector: "app=web"},
+			namespace: "default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace, pods, err := New(kube).TargetPods(tt.target)
+			if err != nil {
+				t.Fatalf("TargetPods() error = %v", err)
+			}
+
+			if namespace != tt.namespace || !reflect.DeepEqual(pods, tt.pods) {
+				t.Errorf("TargetPods() = %s, %q, want %s, %q", namespace, pods, tt.namespace, tt.pods)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package pumba
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+type (
+	// FieldError struct define single invalid field of worker
+	FieldError struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	}
+
+	// ValidationError struct returned when worker is invalid
+	// hold every invalid field of the worker
+	ValidationError struct {
+		Errors []FieldError
+	}
+)
+
+// Error function return all invalid fields in single line
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Errors))
+	for _, f := range e.Errors {
+		fields = append(fields, fmt.Sprintf("%s: %s", f.Field, f.Message))
+	}
+	return "invalid pumba worker: " + strings.Join(fields, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// netEmDistributions define valid netem delay distribution
+var netEmDistributions = map[string]bool{
+	"":             true,
+	"uniform":      true,
+	"normal":       true,
+	"pareto":       true,
+	"paretonormal": true,
+}
+
+// ipTablesProtocols define valid iptables protocol filter
+var ipTablesProtocols = map[string]bool{
+	"":     true,
+	"any":  true,
+	"tcp":  true,
+	"udp":  true,
+	"icmp": true,
+}
+
+// Validate function check worker spec before it run on cluster
+// returning *ValidationError that hold every invalid field
+func (w Worker) Validate() error {
+	var (
+		verr = &ValidationError{}
+	)
+
+	interval, err := time.ParseDuration(w.interval)
+	switch {
+	case w.interval == "":
+		verr.add("interval", "is required")
+	case err != nil:
+		verr.add("interval", "invalid duration %q", w.interval)
+	case interval <= 0:
+		verr.add("interval", "must be positive")
+	}
+
+	w.validateTarget(verr)
+
+	for _, optionMode := range w.optionModes {
+		if optionMode != w.mode {
+			verr.add("mode", "%q disagree with given %s options", w.mode, optionMode)
+		}
+	}
+
+	switch w.mode {
+	case CommandNetEm:
+		w.validateNetEm(verr, interval)
+	case CommandPause:
+		validateDuration(verr, "pause.duration", w.pauseOptions.Duration, interval, true)
+	case CommandKill:
+		if w.killOptions.Signal != "" && !strings.HasPrefix(w.killOptions.Signal, "SIG") {
+			verr.add("kill.signal", "invalid signal %q, eg : SIGKILL", w.killOptions.Signal)
+		}
+		if w.killOptions.Limit < 0 {
+			verr.add("kill.limit", "must not be negative")
+		}
+	case CommandStop:
+		validateDuration(verr, "stop.duration", w.stopOptions.Duration, interval, w.stopOptions.Restart)
+		if w.stopOptions.WaitTime < 0 {
+			verr.add("stop.wait_time", "must not be negative")
+		}
+	case CommandRemove:
+	case CommandStress:
+		w.validateStress(verr, interval)
+	case CommandIPTables:
+		w.validateIPTables(verr, interval)
+	case "":
+		verr.add("mode", "is required")
+	default:
+		verr.add("mode", "unknown mode %q", w.mode)
+	}
+
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func (w Worker) validateTarget(verr *ValidationError) {
+	var set int
+	for _, v := range []string{w.target.Pod, w.target.Deployment, w.target.LabelSelector} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		verr.add("target", "set only one of pod, deployment or label_selector")
+	}
+
+	if w.target.LabelSelector != "" {
+		if _, err := labels.Parse(w.target.LabelSelector); err != nil {
+			verr.add("target.label_selector", "%s", err)
+		}
+	}
+
+	switch w.target.Pick {
+	case "", PickAll:
+	case PickRandom:
+		if w.target.Count < 1 {
+			verr.add("target.count", "must be at least 1 on random pick")
+		}
+	case PickPercent:
+		if w.target.Percent < 1 || w.target.Percent > 100 {
+			verr.add("target.percent", "must be between 1 and 100 on percent pick")
+		}
+	default:
+		verr.add("target.pick", "unknown pick mode %q", w.target.Pick)
+	}
+}
+
+func (w Worker) validateNetEm(verr *ValidationError, interval time.Duration) {
+	opts := w.netEmOptions
+
+	validateDuration(verr, "netem.duration", opts.Duration, interval, true)
+
+	validateIPList(verr, "netem.target_ip_filter", opts.TargetIPFilter)
+
+	switch w.netEmCommand {
+	case CommandNetEmDelay:
+		if opts.Delay.Time <= 0 {
+			verr.add("netem.delay.time", "must be positive")
+		}
+		if opts.Delay.Jitter < 0 {
+			verr.add("netem.delay.jitter", "must not be negative")
+		}
+		if opts.Delay.Correlation < 0 || opts.Delay.Correlation > 100 {
+			verr.add("netem.delay.correlation", "must be between 0 and 100")
+		}
+		if !netEmDistributions[opts.Delay.Distribution] {
+			verr.add("netem.delay.distribution", "unknown distribution %q", opts.Delay.Distribution)
+		}
+	case CommandNetEmLoss:
+		validatePercent(verr, "netem.loss.percent", opts.Loss.Percent, true)
+		validatePercent(verr, "netem.loss.correlation", opts.Loss.Correlation, false)
+	case CommandNetEmLossState:
+		validatePercent(verr, "netem.loss_state.p13", opts.LossState.P13, false)
+		validatePercent(verr, "netem.loss_state.p31", opts.LossState.P31, false)
+		validatePercent(verr, "netem.loss_state.p32", opts.LossState.P32, false)
+		validatePercent(verr, "netem.loss_state.p23", opts.LossState.P23, false)
+		validatePercent(verr, "netem.loss_state.p14", opts.LossState.P14, false)
+	case CommandNetEmLossGEModel:
+		validatePercent(verr, "netem.loss_gemodel.pg", opts.LossGEModel.PG, false)
+		validatePercent(verr, "netem.loss_gemodel.pb", opts.LossGEModel.PB, false)
+		validatePercent(verr, "netem.loss_gemodel.h", opts.LossGEModel.H, false)
+		validatePercent(verr, "netem.loss_gemodel.k", opts.LossGEModel.K, false)
+	case CommandNetEmRate:
+		if opts.Rate.Rate == "" {
+			verr.add("netem.rate.rate", "is required")
+		}
+		if opts.Rate.CellSize < 0 {
+			verr.add("netem.rate.cell_size", "must not be negative")
+		}
+	case CommandNetEmDuplicate:
+		validatePercent(verr, "netem.duplicate.percent", opts.Duplicate.Percent, true)
+		validatePercent(verr, "netem.duplicate.correlation", opts.Duplicate.Correlation, false)
+	case CommandNetEmCorrupt:
+		validatePercent(verr, "netem.corrupt.percent", opts.Corrupt.Percent, true)
+		validatePercent(verr, "netem.corrupt.correlation", opts.Corrupt.Correlation, false)
+	case "":
+		verr.add("netem_command", "is required")
+	default:
+		verr.add("netem_command", "unknown netem command %q", w.netEmCommand)
+	}
+}
+
+func (w Worker) validateStress(verr *ValidationError, interval time.Duration) {
+	opts := w.stressOptions
+
+	validateDuration(verr, "stress.duration", opts.Duration, interval, true)
+
+	stressors := opts.Stressors
+	if stressors.CPUWorkers <= 0 && stressors.VMWorkers <= 0 && stressors.IOWorkers <= 0 {
+		verr.add("stress.stressors", "at least one of cpu_workers, vm_workers or io_workers is required")
+	}
+
+	if stressors.CPUWorkers < 0 || stressors.VMWorkers < 0 || stressors.IOWorkers < 0 {
+		verr.add("stress.stressors", "workers must not be negative")
+	}
+
+	if stressors.CPULoad < 0 || stressors.CPULoad > 100 {
+		verr.add("stress.stressors.cpu_load", "must be between 0 and 100")
+	}
+
+	if stressors.Timeout != "" {
+		if _, err := time.ParseDuration(stressors.Timeout); err != nil {
+			verr.add("stress.stressors.timeout", "invalid duration %q", stressors.Timeout)
+		}
+	}
+}
+
+func (w Worker) validateIPTables(verr *ValidationError, interval time.Duration) {
+	opts := w.ipTablesOptions
+
+	validateDuration(verr, "iptables.duration", opts.Duration, interval, true)
+
+	if !ipTablesProtocols[opts.Protocol] {
+		verr.add("iptables.protocol", "unknown protocol %q", opts.Protocol)
+	}
+
+	validateIPList(verr, "iptables.source", opts.Source)
+	validateIPList(verr, "iptables.destination", opts.Destination)
+	validatePortList(verr, "iptables.src_port", opts.SrcPort)
+	validatePortList(verr, "iptables.dst_port", opts.DstPort)
+
+	switch opts.Mode {
+	case "", IPTablesLossRandom:
+		if opts.Probability <= 0 || opts.Probability > 1 {
+			verr.add("iptables.probability", "must be greater than 0 and at most 1")
+		}
+	case IPTablesLossNth:
+		if opts.Every < 1 {
+			verr.add("iptables.every", "must be at least 1")
+		}
+		if opts.Packet < 0 || (opts.Every > 0 && opts.Packet >= opts.Every) {
+			verr.add("iptables.packet", "must be between 0 and every - 1")
+		}
+	default:
+		verr.add("iptables.mode", "unknown loss mode %q", opts.Mode)
+	}
+}
+
+// validateIPList function check comma separated IP or CIDR list
+func validateIPList(verr *ValidationError, field, list string) {
+	for _, ip := range splitList(list) {
+		_, _, cidrErr := net.ParseCIDR(ip)
+		if net.ParseIP(ip) == nil && cidrErr != nil {
+			verr.add(field, "invalid IP or CIDR %q", ip)
+		}
+	}
+}
+
+// validatePortList function check comma separated port list
+func validatePortList(verr *ValidationError, field, list string) {
+	for _, port := range splitList(list) {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			verr.add(field, "invalid port %q", port)
+		}
+	}
+}
+
+// validateDuration function check chaos duration syntax
+// and make sure it's shorter than worker interval
+func validateDuration(verr *ValidationError, field, value string, interval time.Duration, required bool) {
+	if value == "" {
+		if required {
+			verr.add(field, "is required")
+		}
+		return
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		verr.add(field, "invalid duration %q", value)
+		return
+	}
+
+	if duration <= 0 {
+		verr.add(field, "must be positive")
+		return
+	}
+
+	if interval > 0 && duration >= interval {
+		verr.add(field, "must be shorter than interval %s", interval)
+	}
+}
+
+func validatePercent(verr *ValidationError, field string, value float64, required bool) {
+	if required && value <= 0 {
+		verr.add(field, "must be greater than 0")
+		return
+	}
+
+	if value < 0 || value > 100 {
+		verr.add(field, "must be between 0 and 100")
+	}
+}
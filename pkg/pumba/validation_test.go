@@ -0,0 +1,264 @@
+package pumba
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWorkerValidate(t *testing.T) {
+	var (
+		e      = New(nil)
+		target = Target{Deployment: "redis"}
+	)
+
+	tests := []struct {
+		name string
+		spec WorkerSpec
+		// fields invalid fields of the worker, empty when worker valid
+		fields []string
+	}{
+		{
+			name: "valid netem",
+			spec: WorkerSpec{Target: target, Interval: "1m", NetEmCommand: CommandNetEmDelay, NetEm: &NetEmOptions{
+				Duration: "30s",
+				Delay:    DelayOptions{Time: 100, Correlation: 100},
+			}},
+		},
+		{
+			name: "valid iptables nth",
+			spec: WorkerSpec{Target: target, Interval: "1m", IPTables: &IPTablesOptions{
+				Duration: "30s",
+				Mode:     IPTablesLossNth,
+				Every:    2,
+			}},
+		},
+		{
+			name:   "missing interval and mode",
+			spec:   WorkerSpec{Target: target},
+			fields: []string{"interval", "mode"},
+		},
+		{
+			name:   "invalid interval",
+			spec:   WorkerSpec{Target: target, Interval: "soon", Kill: &KillOptions{}},
+			fields: []string{"interval"},
+		},
+		{
+			name:   "negative interval",
+			spec:   WorkerSpec{Target: target, Interval: "-1m", Kill: &KillOptions{}},
+			fields: []string{"interval"},
+		},
+		{
+			name:   "unknown mode",
+			spec:   WorkerSpec{Target: target, Interval: "1m", Mode: "explode"},
+			fields: []string{"mode"},
+		},
+		{
+			name:   "mode disagree with options",
+			spec:   WorkerSpec{Target: target, Interval: "1m", Mode: CommandKill, Pause: &PauseOptions{Duration: "10s"}},
+			fields: []string{"mode"},
+		},
+		{
+			name:   "many target workloads",
+			spec:   WorkerSpec{Target: Target{Pod: "redis-0", Deployment: "redis"}, Interval: "1m", Remove: &RemoveOptions{}},
+			fields: []string{"target"},
+		},
+		{
+			name:   "invalid label selector",
+			spec:   WorkerSpec{Target: Target{LabelSelector: "app in (redis"}, Interval: "1m", Remove: &RemoveOptions{}},
+			fields: []string{"target.label_selector"},
+		},
+		{
+			name:   "random pick without count",
+			spec:   WorkerSpec{Target: Target{Deployment: "redis", Pick: PickRandom}, Interval: "1m", Remove: &RemoveOptions{}},
+			fields: []string{"target.count"},
+		},
+		{
+			name:   "percent pick out of range",
+			spec:   WorkerSpec{Target: Target{Deployment: "redis", Pick: PickPercent, Percent: 101}, Interval: "1m", Remove: &RemoveOptions{}},
+			fields: []string{"target.percent"},
+		},
+		{
+			name:   "unknown pick",
+			spec:   WorkerSpec{Target: Target{Deployment: "redis", Pick: "first"}, Interval: "1m", Remove: &RemoveOptions{}},
+			fields: []string{"target.pick"},
+		},
+		{
+			name:   "netem without command",
+			spec:   WorkerSpec{Target: target, Interval: "1m", NetEm: &NetEmOptions{Duration: "30s"}},
+			fields: []string{"netem_command"},
+		},
+		{
+			name:   "unknown netem command",
+			spec:   WorkerSpec{Target: target, Interval: "1m", NetEmCommand: "reorder", NetEm: &NetEmOptions{Duration: "30s"}},
+			fields: []string{"netem_command"},
+		},
+		{
+			name: "invalid netem delay",
+			spec: WorkerSpec{Target: target, Interval: "1m", NetEmCommand: CommandNetEmDelay, NetEm: &NetEmOptions{
+				Duration:       "2m",
+				TargetIPFilter: "10.0.0.300",
+				Delay:          DelayOptions{Jitter: -1, Correlation: 101, Distribution: "poisson"},
+			}},
+			fields: []string{
+				"netem.duration", "netem.target_ip_filter", "netem.delay.time",
+				"netem.delay.jitter", "netem.delay.correlation", "netem.delay.distribution",
+			},
+		},
+		{
+			name: "negative netem delay correlation",
+			spec: WorkerSpec{Target: target, Interval: "1m", NetEmCommand: CommandNetEmDelay, NetEm: &NetEmOptions{
+				Duration: "30s",
+				Delay:    DelayOptions{Time: 100, Correlation: -1},
+			}},
+			fields: []string{"netem.delay.correlation"},
+		},
+		{
+			name: "invalid netem loss",
+			spec: WorkerSpec{Target: target, Interval: "1m", NetEmCommand: CommandNetEmLoss, NetEm: &NetEmOptions{
+				Loss: LossOptions{Correlation: 150},
+			}},
+			fields: []string{"netem.duration", "netem.loss.percent", "netem.loss.correlation"},
+		},
+		{
+			name: "invalid netem loss state",
+			spec: WorkerSpec{Target: target, Interval: "1m", NetEmCommand: CommandNetEmLossState, NetEm: &NetEmOptions{
+				Duration:  "30s",
+				LossState: LossStateOptions{P13: -1, P14: 200},
+			}},
+			fields: []string{"netem.loss_state.p13", "netem.loss_state.p14"},
+		},
+		{
+			name: "invalid netem loss gemodel",
+			spec: WorkerSpec{Target: target, Interval: "1m", NetEmCommand: CommandNetEmLossGEModel, NetEm: &NetEmOptions{
+				Duration:    "30s",
+				LossGEModel: LossGEModelOptions{PG: 101},
+			}},
+			fields: []string{"netem.loss_gemodel.pg"},
+		},
+		{
+			name: "invalid netem rate",
+			spec: WorkerSpec{Target: target, Interval: "1m", NetEmCommand: CommandNetEmRate, NetEm: &NetEmOptions{
+				Duration: "30s",
+				Rate:     RateOptions{CellSize: -1},
+			}},
+			fields: []string{"netem.rate.rate", "netem.rate.cell_size"},
+		},
+		{
+			name: "invalid netem duplicate",
+			spec: WorkerSpec{Target: target, Interval: "1m", NetEmCommand: CommandNetEmDuplicate, NetEm: &NetEmOptions{
+				Duration: "30s",
+			}},
+			fields: []string{"netem.duplicate.percent"},
+		},
+		{
+			name: "invalid netem corrupt",
+			spec: WorkerSpec{Target: target, Interval: "1m", NetEmCommand: CommandNetEmCorrupt, NetEm: &NetEmOptions{
+				Duration: "30s",
+				Corrupt:  CorruptOptions{Percent: 101, Correlation: -1},
+			}},
+			fields: []string{"netem.corrupt.percent", "netem.corrupt.correlation"},
+		},
+		{
+			name:   "pause without duration",
+			spec:   WorkerSpec{Target: target, Interval: "1m", Pause: &PauseOptions{}},
+			fields: []string{"pause.duration"},
+		},
+		{
+			name:   "pause longer than interval",
+			spec:   WorkerSpec{Target: target, Interval: "1m", Pause: &PauseOptions{Duration: "1m"}},
+			fields: []string{"pause.duration"},
+		},
+		{
+			name:   "invalid kill",
+			spec:   WorkerSpec{Target: target, Interval: "1m", Kill: &KillOptions{Signal: "TERM", Limit: -1}},
+			fields: []string{"kill.signal", "kill.limit"},
+		},
+		{
+			name:   "stop restart without duration",
+			spec:   WorkerSpec{Target: target, Interval: "1m", Stop: &StopOptions{Restart: true, WaitTime: -1}},
+			fields: []string{"stop.duration", "stop.wait_time"},
+		},
+		{
+			name: "stress without stressors",
+			spec: WorkerSpec{Target: target, Interval: "1m", Stress: &StressOptions{
+				Stressors: StressorSpec{CPULoad: 120, Timeout: "later"},
+			}},
+			fields: []string{"stress.duration", "stress.stressors", "stress.stressors.cpu_load", "stress.stressors.timeout"},
+		},
+		{
+			name: "stress negative workers",
+			spec: WorkerSpec{Target: target, Interval: "1m", Stress: &StressOptions{
+				Duration:  "30s",
+				Stressors: StressorSpec{CPUWorkers: 1, VMWorkers: -1},
+			}},
+			fields: []string{"stress.stressors"},
+		},
+		{
+			name: "invalid iptables filter",
+			spec: WorkerSpec{Target: target, Interval: "1m", IPTables: &IPTablesOptions{
+				Duration:    "30s",
+				Protocol:    "sctp",
+				Source:      "localhost",
+				Destination: "10.0.0.0/33",
+				SrcPort:     "0",
+				DstPort:     "http",
+				Probability: 0.5,
+			}},
+			fields: []string{"iptables.protocol", "iptables.source", "iptables.destination", "iptables.src_port", "iptables.dst_port"},
+		},
+		{
+			name: "iptables random probability out of range",
+			spec: WorkerSpec{Target: target, Interval: "1m", IPTables: &IPTablesOptions{
+				Duration:    "30s",
+				Probability: 1.5,
+			}},
+			fields: []string{"iptables.probability"},
+		},
+		{
+			name: "invalid iptables nth",
+			spec: WorkerSpec{Target: target, Interval: "1m", IPTables: &IPTablesOptions{
+				Duration: "30s",
+				Mode:     IPTablesLossNth,
+				Every:    2,
+				Packet:   2,
+			}},
+			fields: []string{"iptables.packet"},
+		},
+		{
+			name: "unknown iptables mode",
+			spec: WorkerSpec{Target: target, Interval: "1m", IPTables: &IPTablesOptions{
+				Duration: "30s",
+				Mode:     "burst",
+			}},
+			fields: []string{"iptables.mode"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := e.NewPumbaWorkerFromSpec(tt.spec).Validate()
+
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() error = %v, want *ValidationError", err)
+			}
+
+			fields := make([]string, 0, len(verr.Errors))
+			for _, f := range verr.Errors {
+				fields = append(fields, f.Field)
+			}
+
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("invalid fields = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}